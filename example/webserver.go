@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/k-samuel/go-faceted-search/pkg/filter"
@@ -104,7 +105,10 @@ func loadFacet(filePath string, db map[int64]map[string]interface{}, extract rec
 		if extract != nil {
 			result = extract(result)
 		}
-		index.Add(id, result)
+		if err := index.Add(id, result); err != nil {
+			log.Println("skip record", id, err)
+			continue
+		}
 		counter++
 	}
 	index.CommitChanges()
@@ -119,8 +123,13 @@ func facetHandler(w http.ResponseWriter, r *http.Request, search *facet.Search,
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	filterResult, _ := search.AggregateFilters(filters, []int64{})
-	result := map[string]interface{}{"filters": filterResult, "results": findResults(search, filters, db)}
+	// stop search if client has gone
+	filterResult, err := search.AggregateFiltersContext(r.Context(), filters, []int64{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	result := map[string]interface{}{"filters": filterResult, "results": findResults(r.Context(), search, filters, db)}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
@@ -155,20 +164,15 @@ func extractFilters(r *http.Request) (filters []filter.FilterInterface, err erro
 	return
 }
 
-func findResults(search *facet.Search, filters []filter.FilterInterface, db inmemoryDb) (result map[string]interface{}) {
+func findResults(ctx context.Context, search *facet.Search, filters []filter.FilterInterface, db inmemoryDb) (result map[string]interface{}) {
 	pageLimit := 25
-	resultIds, _ := search.Find(filters, []int64{})
+	page, _ := search.QueryContext(ctx, &facet.Query{Filters: filters, Limit: pageLimit})
 
 	records := make([]map[string]interface{}, 0, pageLimit)
-	result = map[string]interface{}{"count": len(resultIds), "limit": pageLimit, "data": &records}
-	if len(resultIds) > 0 {
-		for _, v := range resultIds {
-			if len(records) == pageLimit {
-				return
-			}
-			if dat, ok := db[v]; ok {
-				records = append(records, dat)
-			}
+	result = map[string]interface{}{"count": page.Total, "limit": pageLimit, "data": &records}
+	for _, v := range page.Ids {
+		if dat, ok := db[v]; ok {
+			records = append(records, dat)
 		}
 	}
 	return
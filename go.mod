@@ -1,3 +1,5 @@
 module github.com/k-samuel/go-faceted-search
 
-go 1.17
\ No newline at end of file
+go 1.17
+
+require golang.org/x/text v0.13.0
@@ -0,0 +1,248 @@
+package bitmap
+
+import "sort"
+
+/*
+ *  Data Structure (roaring bitmap)
+ *   Bitmap {
+ *      keys: [] - sorted list of high 48 bits of record id
+ *      containers: [] - low 16 bits of record id for each key
+ *           container {
+ *               array: [] - sorted list of values (sparse container, up to 4096 values)
+ *               bits: [] - 65536 bits (dense container)
+ *           }
+ *   }
+ */
+
+// Bitmap - compressed bitmap of record id
+type Bitmap struct {
+	keys       []uint64
+	containers []*container
+}
+
+// New - create empty bitmap
+func New() *Bitmap {
+	return &Bitmap{}
+}
+
+// FromIds - create bitmap from list of record id (sorted lists are processed faster)
+func FromIds(ids []int64) *Bitmap {
+	bm := New()
+	for _, id := range ids {
+		bm.Add(id)
+	}
+	return bm
+}
+
+// split record id into container key and value,
+// sign bit is flipped to keep order of negative values
+func split(id int64) (uint64, uint16) {
+	u := uint64(id) ^ (1 << 63)
+	return u >> 16, uint16(u)
+}
+
+func join(key uint64, low uint16) int64 {
+	return int64((key<<16 | uint64(low)) ^ (1 << 63))
+}
+
+// find - get container position for the key, returns false if there is no container
+func (bm *Bitmap) find(key uint64) (int, bool) {
+	n := len(bm.keys)
+	// fast path for sequential adding
+	if n > 0 && bm.keys[n-1] == key {
+		return n - 1, true
+	}
+	if n > 0 && bm.keys[n-1] < key {
+		return n, false
+	}
+	pos := sort.Search(n, func(i int) bool { return bm.keys[i] >= key })
+	return pos, pos < n && bm.keys[pos] == key
+}
+
+// Add - add record id into bitmap
+func (bm *Bitmap) Add(id int64) {
+	key, low := split(id)
+	pos, ok := bm.find(key)
+	if !ok {
+		bm.keys = append(bm.keys, 0)
+		copy(bm.keys[pos+1:], bm.keys[pos:])
+		bm.keys[pos] = key
+		bm.containers = append(bm.containers, nil)
+		copy(bm.containers[pos+1:], bm.containers[pos:])
+		bm.containers[pos] = &container{}
+	}
+	bm.containers[pos].add(low)
+}
+
+// Remove - remove record id from bitmap, returns true if id was found
+func (bm *Bitmap) Remove(id int64) bool {
+	key, low := split(id)
+	pos, ok := bm.find(key)
+	if !ok {
+		return false
+	}
+	c := bm.containers[pos]
+	if !c.remove(low) {
+		return false
+	}
+	if c.n == 0 {
+		bm.keys = append(bm.keys[:pos], bm.keys[pos+1:]...)
+		bm.containers = append(bm.containers[:pos], bm.containers[pos+1:]...)
+	}
+	return true
+}
+
+// Contains - check if bitmap contains record id
+func (bm *Bitmap) Contains(id int64) bool {
+	key, low := split(id)
+	pos, ok := bm.find(key)
+	return ok && bm.containers[pos].contains(low)
+}
+
+// Cardinality - get count of record id in bitmap
+func (bm *Bitmap) Cardinality() int {
+	result := 0
+	for _, c := range bm.containers {
+		result += c.n
+	}
+	return result
+}
+
+// IsEmpty - check if bitmap has no records
+func (bm *Bitmap) IsEmpty() bool {
+	return len(bm.containers) == 0
+}
+
+// ToArray - get sorted list of record id
+func (bm *Bitmap) ToArray() []int64 {
+	result := make([]int64, 0, bm.Cardinality())
+	for i, c := range bm.containers {
+		result = c.appendTo(result, bm.keys[i])
+	}
+	return result
+}
+
+// Clone - create copy of bitmap
+func (bm *Bitmap) Clone() *Bitmap {
+	result := &Bitmap{
+		keys:       make([]uint64, len(bm.keys)),
+		containers: make([]*container, len(bm.containers)),
+	}
+	copy(result.keys, bm.keys)
+	for i, c := range bm.containers {
+		result.containers[i] = c.clone()
+	}
+	return result
+}
+
+// And - keep only records which are present in other bitmap
+func (bm *Bitmap) And(other *Bitmap) {
+	keys := make([]uint64, 0, len(bm.keys))
+	containers := make([]*container, 0, len(bm.containers))
+	i, j := 0, 0
+	for i < len(bm.keys) && j < len(other.keys) {
+		switch {
+		case bm.keys[i] < other.keys[j]:
+			i++
+		case bm.keys[i] > other.keys[j]:
+			j++
+		default:
+			if c := andContainers(bm.containers[i], other.containers[j]); c.n > 0 {
+				keys = append(keys, bm.keys[i])
+				containers = append(containers, c)
+			}
+			i++
+			j++
+		}
+	}
+	bm.keys = keys
+	bm.containers = containers
+}
+
+// Or - add records of other bitmap
+func (bm *Bitmap) Or(other *Bitmap) {
+	keys := make([]uint64, 0, len(bm.keys)+len(other.keys))
+	containers := make([]*container, 0, len(bm.containers)+len(other.containers))
+	i, j := 0, 0
+	for i < len(bm.keys) || j < len(other.keys) {
+		switch {
+		case j == len(other.keys) || (i < len(bm.keys) && bm.keys[i] < other.keys[j]):
+			keys = append(keys, bm.keys[i])
+			containers = append(containers, bm.containers[i])
+			i++
+		case i == len(bm.keys) || bm.keys[i] > other.keys[j]:
+			keys = append(keys, other.keys[j])
+			containers = append(containers, other.containers[j].clone())
+			j++
+		default:
+			keys = append(keys, bm.keys[i])
+			containers = append(containers, orContainers(bm.containers[i], other.containers[j]))
+			i++
+			j++
+		}
+	}
+	bm.keys = keys
+	bm.containers = containers
+}
+
+// AndNot - remove records which are present in other bitmap
+func (bm *Bitmap) AndNot(other *Bitmap) {
+	keys := make([]uint64, 0, len(bm.keys))
+	containers := make([]*container, 0, len(bm.containers))
+	j := 0
+	for i, key := range bm.keys {
+		for j < len(other.keys) && other.keys[j] < key {
+			j++
+		}
+		if j == len(other.keys) || other.keys[j] != key {
+			keys = append(keys, key)
+			containers = append(containers, bm.containers[i])
+			continue
+		}
+		if c := andNotContainers(bm.containers[i], other.containers[j]); c.n > 0 {
+			keys = append(keys, key)
+			containers = append(containers, c)
+		}
+	}
+	bm.keys = keys
+	bm.containers = containers
+}
+
+// AndCardinality - get count of records present in both bitmaps
+func (bm *Bitmap) AndCardinality(other *Bitmap) int {
+	result := 0
+	i, j := 0, 0
+	for i < len(bm.keys) && j < len(other.keys) {
+		switch {
+		case bm.keys[i] < other.keys[j]:
+			i++
+		case bm.keys[i] > other.keys[j]:
+			j++
+		default:
+			result += andCardinality(bm.containers[i], other.containers[j])
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// Intersects - check if bitmaps have at least one common record
+func (bm *Bitmap) Intersects(other *Bitmap) bool {
+	i, j := 0, 0
+	for i < len(bm.keys) && j < len(other.keys) {
+		switch {
+		case bm.keys[i] < other.keys[j]:
+			i++
+		case bm.keys[i] > other.keys[j]:
+			j++
+		default:
+			if intersects(bm.containers[i], other.containers[j]) {
+				return true
+			}
+			i++
+			j++
+		}
+	}
+	return false
+}
@@ -0,0 +1,328 @@
+package bitmap
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// arrayMaxSize - max count of values in sparse container,
+// dense container with 65536 bits takes the same memory
+const arrayMaxSize = 4096
+
+// bitmapWords - count of words in dense container
+const bitmapWords = 1024
+
+// container - low 16 bits of record id stored as sorted array or as bitmap
+type container struct {
+	array []uint16
+	bits  []uint64
+	n     int
+}
+
+func (c *container) isBitmap() bool {
+	return c.bits != nil
+}
+
+func (c *container) add(v uint16) {
+	if c.isBitmap() {
+		w, mask := v>>6, uint64(1)<<(v&63)
+		if c.bits[w]&mask == 0 {
+			c.bits[w] |= mask
+			c.n++
+		}
+		return
+	}
+	// fast path for sequential adding
+	if c.n == 0 || c.array[c.n-1] < v {
+		c.array = append(c.array, v)
+	} else {
+		pos := sort.Search(c.n, func(i int) bool { return c.array[i] >= v })
+		if c.array[pos] == v {
+			return
+		}
+		c.array = append(c.array, 0)
+		copy(c.array[pos+1:], c.array[pos:])
+		c.array[pos] = v
+	}
+	c.n++
+	if c.n > arrayMaxSize {
+		c.toBitmap()
+	}
+}
+
+func (c *container) remove(v uint16) bool {
+	if c.isBitmap() {
+		w, mask := v>>6, uint64(1)<<(v&63)
+		if c.bits[w]&mask == 0 {
+			return false
+		}
+		c.bits[w] &^= mask
+		c.n--
+		c.normalize()
+		return true
+	}
+	pos := sort.Search(c.n, func(i int) bool { return c.array[i] >= v })
+	if pos == c.n || c.array[pos] != v {
+		return false
+	}
+	c.array = append(c.array[:pos], c.array[pos+1:]...)
+	c.n--
+	return true
+}
+
+func (c *container) contains(v uint16) bool {
+	if c.isBitmap() {
+		return c.bits[v>>6]&(uint64(1)<<(v&63)) != 0
+	}
+	pos := sort.Search(c.n, func(i int) bool { return c.array[i] >= v })
+	return pos < c.n && c.array[pos] == v
+}
+
+func (c *container) appendTo(result []int64, key uint64) []int64 {
+	if !c.isBitmap() {
+		for _, v := range c.array {
+			result = append(result, join(key, v))
+		}
+		return result
+	}
+	for w, word := range c.bits {
+		for word != 0 {
+			t := bits.TrailingZeros64(word)
+			result = append(result, join(key, uint16(w<<6+t)))
+			word &= word - 1
+		}
+	}
+	return result
+}
+
+func (c *container) clone() *container {
+	result := &container{n: c.n}
+	if c.isBitmap() {
+		result.bits = make([]uint64, bitmapWords)
+		copy(result.bits, c.bits)
+	} else {
+		result.array = make([]uint16, len(c.array))
+		copy(result.array, c.array)
+	}
+	return result
+}
+
+// toBitmap - convert sparse container into dense one
+func (c *container) toBitmap() {
+	c.bits = make([]uint64, bitmapWords)
+	for _, v := range c.array {
+		c.bits[v>>6] |= uint64(1) << (v & 63)
+	}
+	c.array = nil
+}
+
+// normalize - convert dense container into sparse one if it has few values
+func (c *container) normalize() {
+	if !c.isBitmap() || c.n > arrayMaxSize {
+		return
+	}
+	array := make([]uint16, 0, c.n)
+	for w, word := range c.bits {
+		for word != 0 {
+			t := bits.TrailingZeros64(word)
+			array = append(array, uint16(w<<6+t))
+			word &= word - 1
+		}
+	}
+	c.array = array
+	c.bits = nil
+}
+
+func andContainers(a, b *container) *container {
+	if a.isBitmap() && b.isBitmap() {
+		result := &container{bits: make([]uint64, bitmapWords)}
+		for i := range a.bits {
+			result.bits[i] = a.bits[i] & b.bits[i]
+			result.n += bits.OnesCount64(result.bits[i])
+		}
+		result.normalize()
+		return result
+	}
+	if a.isBitmap() {
+		a, b = b, a
+	}
+	result := &container{array: make([]uint16, 0, a.n)}
+	if b.isBitmap() {
+		for _, v := range a.array {
+			if b.contains(v) {
+				result.array = append(result.array, v)
+			}
+		}
+	} else {
+		i, j := 0, 0
+		for i < a.n && j < b.n {
+			switch {
+			case a.array[i] < b.array[j]:
+				i++
+			case a.array[i] > b.array[j]:
+				j++
+			default:
+				result.array = append(result.array, a.array[i])
+				i++
+				j++
+			}
+		}
+	}
+	result.n = len(result.array)
+	return result
+}
+
+func orContainers(a, b *container) *container {
+	if !a.isBitmap() && !b.isBitmap() {
+		result := &container{array: make([]uint16, 0, a.n+b.n)}
+		i, j := 0, 0
+		for i < a.n || j < b.n {
+			switch {
+			case j == b.n || (i < a.n && a.array[i] < b.array[j]):
+				result.array = append(result.array, a.array[i])
+				i++
+			case i == a.n || a.array[i] > b.array[j]:
+				result.array = append(result.array, b.array[j])
+				j++
+			default:
+				result.array = append(result.array, a.array[i])
+				i++
+				j++
+			}
+		}
+		result.n = len(result.array)
+		if result.n > arrayMaxSize {
+			result.toBitmap()
+		}
+		return result
+	}
+	if !a.isBitmap() {
+		a, b = b, a
+	}
+	result := a.clone()
+	if b.isBitmap() {
+		result.n = 0
+		for i := range result.bits {
+			result.bits[i] |= b.bits[i]
+			result.n += bits.OnesCount64(result.bits[i])
+		}
+		return result
+	}
+	for _, v := range b.array {
+		result.add(v)
+	}
+	return result
+}
+
+func andNotContainers(a, b *container) *container {
+	if a.isBitmap() {
+		result := a.clone()
+		if b.isBitmap() {
+			result.n = 0
+			for i := range result.bits {
+				result.bits[i] &^= b.bits[i]
+				result.n += bits.OnesCount64(result.bits[i])
+			}
+		} else {
+			for _, v := range b.array {
+				w, mask := v>>6, uint64(1)<<(v&63)
+				if result.bits[w]&mask != 0 {
+					result.bits[w] &^= mask
+					result.n--
+				}
+			}
+		}
+		result.normalize()
+		return result
+	}
+	result := &container{array: make([]uint16, 0, a.n)}
+	if b.isBitmap() {
+		for _, v := range a.array {
+			if !b.contains(v) {
+				result.array = append(result.array, v)
+			}
+		}
+	} else {
+		j := 0
+		for _, v := range a.array {
+			for j < b.n && b.array[j] < v {
+				j++
+			}
+			if j < b.n && b.array[j] == v {
+				continue
+			}
+			result.array = append(result.array, v)
+		}
+	}
+	result.n = len(result.array)
+	return result
+}
+
+func andCardinality(a, b *container) int {
+	result := 0
+	if a.isBitmap() && b.isBitmap() {
+		for i := range a.bits {
+			result += bits.OnesCount64(a.bits[i] & b.bits[i])
+		}
+		return result
+	}
+	if a.isBitmap() {
+		a, b = b, a
+	}
+	if b.isBitmap() {
+		for _, v := range a.array {
+			if b.contains(v) {
+				result++
+			}
+		}
+		return result
+	}
+	i, j := 0, 0
+	for i < a.n && j < b.n {
+		switch {
+		case a.array[i] < b.array[j]:
+			i++
+		case a.array[i] > b.array[j]:
+			j++
+		default:
+			result++
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+func intersects(a, b *container) bool {
+	if a.isBitmap() && b.isBitmap() {
+		for i := range a.bits {
+			if a.bits[i]&b.bits[i] != 0 {
+				return true
+			}
+		}
+		return false
+	}
+	if a.isBitmap() {
+		a, b = b, a
+	}
+	if b.isBitmap() {
+		for _, v := range a.array {
+			if b.contains(v) {
+				return true
+			}
+		}
+		return false
+	}
+	i, j := 0, 0
+	for i < a.n && j < b.n {
+		switch {
+		case a.array[i] < b.array[j]:
+			i++
+		case a.array[i] > b.array[j]:
+			j++
+		default:
+			return true
+		}
+	}
+	return false
+}
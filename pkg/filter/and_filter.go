@@ -0,0 +1,85 @@
+package filter
+
+import (
+	"github.com/k-samuel/go-faceted-search/pkg/bitmap"
+	"github.com/k-samuel/go-faceted-search/pkg/index"
+)
+
+// AndFilter - find records matching all filters (composite filter)
+type AndFilter struct {
+	Filters []FilterInterface
+}
+
+// GetFieldName - composite filter has no field
+func (filter *AndFilter) GetFieldName() string {
+	return ""
+}
+
+// FilterResults - composite filter can not be applied to the single field
+func (filter *AndFilter) FilterResults(field *index.Field, inputKeys []int64) (result []int64, err error) {
+	return make([]int64, 0, 0), ErrCompositeFilter
+}
+
+// FilterIndex - find records matching all filters, empty input list means all records
+func (filter *AndFilter) FilterIndex(idx *index.Index, inputKeys []int64) (result []int64, err error) {
+	if len(filter.Filters) == 0 {
+		return allRecords(idx, inputKeys), err
+	}
+	result = inputKeys
+	for _, fl := range filter.Filters {
+		result, err = Apply(idx, fl, result)
+		if err != nil {
+			return make([]int64, 0, 0), err
+		}
+		// empty result can not be limited by other filters
+		if len(result) == 0 {
+			return result, err
+		}
+	}
+	return result, err
+}
+
+// FilterIndexBitmap - find records matching all filters using bitmaps, nil input means all records
+func (filter *AndFilter) FilterIndexBitmap(idx *index.Index, input *bitmap.Bitmap) (result *bitmap.Bitmap, err error) {
+	if len(filter.Filters) == 0 {
+		return allBitmap(idx, input), err
+	}
+	result = input
+	for _, fl := range filter.Filters {
+		result, err = ApplyBitmap(idx, fl, result)
+		if err != nil {
+			return bitmap.New(), err
+		}
+		if result.IsEmpty() {
+			return result, err
+		}
+	}
+	return result, err
+}
+
+// HasField - check if filter has conditions for the field
+func (filter *AndFilter) HasField(fieldName string) bool {
+	for _, fl := range filter.Filters {
+		if HasField(fl, fieldName) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithoutField - get filter without conditions for the field, other conditions are kept
+func (filter *AndFilter) WithoutField(fieldName string) FilterInterface {
+	if !filter.HasField(fieldName) {
+		return filter
+	}
+	filters := make([]FilterInterface, 0, len(filter.Filters))
+	for _, fl := range filter.Filters {
+		if fl = WithoutField(fl, fieldName); fl != nil {
+			filters = append(filters, fl)
+		}
+	}
+	if len(filters) == 0 {
+		return nil
+	}
+	return &AndFilter{Filters: filters}
+}
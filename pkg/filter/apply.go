@@ -0,0 +1,76 @@
+package filter
+
+import (
+	"github.com/k-samuel/go-faceted-search/pkg/bitmap"
+	"github.com/k-samuel/go-faceted-search/pkg/index"
+)
+
+// Apply - filter index records using any filter, empty input list means all records.
+// Field filters of absent fields find nothing, exclusion filters of absent fields keep the input
+func Apply(idx *index.Index, fl FilterInterface, inputKeys []int64) ([]int64, error) {
+	if composite, ok := fl.(CompositeFilterInterface); ok {
+		return composite.FilterIndex(idx, inputKeys)
+	}
+	exclusion := IsExclusion(fl)
+	fieldName := fl.GetFieldName()
+	if !idx.HasField(fieldName) || !idx.GetField(fieldName).HasValues() {
+		// nothing to exclude
+		if exclusion {
+			return allRecords(idx, inputKeys), nil
+		}
+		// no records with field values
+		return []int64{}, nil
+	}
+	if exclusion && len(inputKeys) == 0 {
+		inputKeys = idx.GetIdList()
+	}
+	return fl.FilterResults(idx.GetField(fieldName), inputKeys)
+}
+
+// ApplyBitmap - filter index records using any filter and bitmaps, nil input means all records.
+// Input bitmap is not changed
+func ApplyBitmap(idx *index.Index, fl FilterInterface, input *bitmap.Bitmap) (*bitmap.Bitmap, error) {
+	if composite, ok := fl.(CompositeFilterInterface); ok {
+		return composite.FilterIndexBitmap(idx, input)
+	}
+	exclusion := IsExclusion(fl)
+	fieldName := fl.GetFieldName()
+	if !idx.HasField(fieldName) || !idx.GetField(fieldName).HasValues() {
+		// nothing to exclude
+		if exclusion {
+			return allBitmap(idx, input), nil
+		}
+		// no records with field values
+		return bitmap.New(), nil
+	}
+	field := idx.GetField(fieldName)
+	if exclusion && input == nil {
+		input = idx.GetIdBitmap()
+	}
+	if bitmapFilter, ok := fl.(BitmapFilterInterface); ok {
+		return bitmapFilter.FilterBitmap(field, input)
+	}
+	// filter without bitmap support
+	var ids []int64
+	if input != nil {
+		ids = input.ToArray()
+	}
+	ids, err := fl.FilterResults(field, ids)
+	return bitmap.FromIds(ids), err
+}
+
+// allRecords - get input list or list of all index records for empty input
+func allRecords(idx *index.Index, inputKeys []int64) []int64 {
+	if len(inputKeys) > 0 {
+		return inputKeys
+	}
+	return idx.GetIdList()
+}
+
+// allBitmap - get copy of input bitmap or bitmap of all index records for nil input
+func allBitmap(idx *index.Index, input *bitmap.Bitmap) *bitmap.Bitmap {
+	if input != nil {
+		return input.Clone()
+	}
+	return idx.GetIdBitmap()
+}
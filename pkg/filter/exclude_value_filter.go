@@ -0,0 +1,62 @@
+package filter
+
+import (
+	"github.com/k-samuel/go-faceted-search/pkg/bitmap"
+	"github.com/k-samuel/go-faceted-search/pkg/index"
+	"github.com/k-samuel/go-faceted-search/pkg/utils"
+)
+
+// ExcludeValueFilter - exclude records with field values from facet data
+type ExcludeValueFilter struct {
+	FieldName string
+	Values    []string
+}
+
+// GetFieldName - get field name
+func (filter *ExcludeValueFilter) GetFieldName() string {
+	return filter.FieldName
+}
+
+// IsExclusion - filter removes records from the input list
+func (filter *ExcludeValueFilter) IsExclusion() bool {
+	return true
+}
+
+// FilterResults - remove records with filter values from input list.
+// Empty input list means empty result, list of all records should be passed to exclude values from all data
+func (filter *ExcludeValueFilter) FilterResults(field *index.Field, inputKeys []int64) (result []int64, err error) {
+
+	if len(inputKeys) == 0 {
+		return make([]int64, 0, 0), err
+	}
+
+	exclude := make([]int64, 0, 100)
+
+	// collect list of record id for different values of one field
+	for _, val := range filter.Values {
+		if !field.HasValue(val) {
+			continue
+		}
+		exclude = append(exclude, field.GetValue(val).GetIds()...)
+	}
+	if len(exclude) > 1 {
+		exclude = utils.Deduplicate(exclude)
+	}
+	return utils.DiffSortedInt(inputKeys, exclude), err
+}
+
+// FilterBitmap - remove records with filter values from input bitmap.
+// Nil input means empty result, bitmap of all records should be passed to exclude values from all data
+func (filter *ExcludeValueFilter) FilterBitmap(field *index.Field, input *bitmap.Bitmap) (result *bitmap.Bitmap, err error) {
+	if input == nil {
+		return bitmap.New(), err
+	}
+	result = input.Clone()
+	for _, val := range filter.Values {
+		if !field.HasValue(val) {
+			continue
+		}
+		result.AndNot(field.GetValue(val).GetBitmap())
+	}
+	return result, err
+}
@@ -0,0 +1,37 @@
+package filter
+
+import (
+	"github.com/k-samuel/go-faceted-search/pkg/bitmap"
+	"github.com/k-samuel/go-faceted-search/pkg/index"
+	"github.com/k-samuel/go-faceted-search/pkg/utils"
+)
+
+// ExistsFilter - filter facet data by records which have any value of the field
+type ExistsFilter struct {
+	FieldName string
+}
+
+// GetFieldName - get field name
+func (filter *ExistsFilter) GetFieldName() string {
+	return filter.FieldName
+}
+
+// FilterResults - filter facet field data
+func (filter *ExistsFilter) FilterResults(field *index.Field, inputKeys []int64) (result []int64, err error) {
+	ids := field.GetRecords().GetIds()
+	if len(inputKeys) == 0 {
+		result = make([]int64, len(ids))
+		copy(result, ids)
+		return result, err
+	}
+	return utils.IntersectSortedInt(ids, inputKeys), err
+}
+
+// FilterBitmap - filter facet field data using bitmaps
+func (filter *ExistsFilter) FilterBitmap(field *index.Field, input *bitmap.Bitmap) (result *bitmap.Bitmap, err error) {
+	result = field.GetRecords().GetBitmap().Clone()
+	if input != nil {
+		result.And(input)
+	}
+	return result, err
+}
@@ -1,7 +1,10 @@
 package filter
 
 import (
+	"errors"
+	"github.com/k-samuel/go-faceted-search/pkg/bitmap"
 	"github.com/k-samuel/go-faceted-search/pkg/index"
+	"github.com/k-samuel/go-faceted-search/pkg/utils"
 )
 
 // FilterInterface - interface for filtering realisation
@@ -9,3 +12,99 @@ type FilterInterface interface {
 	GetFieldName() string
 	FilterResults(facetData *index.Field, inputKeys []int64) (result []int64, err error)
 }
+
+// ExclusionInterface - interface for filters which remove records from the input list.
+// Such filters get the list of all records as input if there are no other limitations
+type ExclusionInterface interface {
+	FilterInterface
+	IsExclusion() bool
+}
+
+// BitmapFilterInterface - interface for filters which can process bitmaps of record id
+// (index.STORAGE_BITMAP) without conversion into lists.
+// Nil input means there is no limitation of records, input bitmap should not be changed
+type BitmapFilterInterface interface {
+	FilterInterface
+	FilterBitmap(facetData *index.Field, input *bitmap.Bitmap) (result *bitmap.Bitmap, err error)
+}
+
+// CompositeFilterInterface - interface for filters which combine other filters (conditions for several fields).
+// Composite filters have empty field name and are applied to the whole index
+type CompositeFilterInterface interface {
+	FilterInterface
+	// FilterIndex - filter index records, empty input list means all records
+	FilterIndex(idx *index.Index, inputKeys []int64) (result []int64, err error)
+	// FilterIndexBitmap - filter index records using bitmaps, nil input means all records
+	FilterIndexBitmap(idx *index.Index, input *bitmap.Bitmap) (result *bitmap.Bitmap, err error)
+	// HasField - check if filter has conditions for the field
+	HasField(fieldName string) bool
+	// WithoutField - get filter without conditions for the field, nil if there are no other conditions
+	WithoutField(fieldName string) FilterInterface
+}
+
+// ErrCompositeFilter - composite filter is applied to the single field
+var ErrCompositeFilter = errors.New("composite filter can not be applied to the field, use FilterIndex")
+
+// IsExclusion - check if filter removes records from the input list (ExclusionInterface)
+func IsExclusion(fl FilterInterface) bool {
+	if ex, ok := fl.(ExclusionInterface); ok {
+		return ex.IsExclusion()
+	}
+	return false
+}
+
+// HasField - check if filter (or composite filter tree) has conditions for the field
+func HasField(fl FilterInterface, fieldName string) bool {
+	if composite, ok := fl.(CompositeFilterInterface); ok {
+		return composite.HasField(fieldName)
+	}
+	return fl.GetFieldName() == fieldName
+}
+
+// WithoutField - get filter without conditions for the field, nil if there are no other conditions
+func WithoutField(fl FilterInterface, fieldName string) FilterInterface {
+	if composite, ok := fl.(CompositeFilterInterface); ok {
+		return composite.WithoutField(fieldName)
+	}
+	if fl.GetFieldName() == fieldName {
+		return nil
+	}
+	return fl
+}
+
+// unionList - get sorted union of value id lists limited by input list (empty input - no limitation)
+func unionList(values []*index.Value, inputKeys []int64) []int64 {
+	var mapLen = len(inputKeys)
+	var hasInput = true
+	if mapLen == 0 {
+		hasInput = false
+		mapLen = 100
+	}
+
+	result := make([]int64, 0, mapLen)
+
+	// collect list of record id for different values of one field
+	for _, list := range values {
+		if hasInput {
+			result = append(result, utils.IntersectSortedInt(list.GetIds(), inputKeys)...)
+		} else {
+			result = append(result, list.GetIds()...)
+		}
+	}
+	if len(result) > 1 {
+		result = utils.Deduplicate(result)
+	}
+	return result
+}
+
+// unionBitmap - get union of value bitmaps limited by input bitmap (nil input - no limitation)
+func unionBitmap(values []*index.Value, input *bitmap.Bitmap) *bitmap.Bitmap {
+	result := bitmap.New()
+	for _, v := range values {
+		result.Or(v.GetBitmap())
+	}
+	if input != nil {
+		result.And(input)
+	}
+	return result
+}
@@ -0,0 +1,43 @@
+package filter
+
+import (
+	"github.com/k-samuel/go-faceted-search/pkg/bitmap"
+	"github.com/k-samuel/go-faceted-search/pkg/index"
+	"github.com/k-samuel/go-faceted-search/pkg/utils"
+)
+
+// MissingFilter - filter facet data by records which have no value of the field.
+// Records without any indexed values are not stored by index and can not be found
+type MissingFilter struct {
+	FieldName string
+}
+
+// GetFieldName - get field name
+func (filter *MissingFilter) GetFieldName() string {
+	return filter.FieldName
+}
+
+// IsExclusion - filter removes records from the input list
+func (filter *MissingFilter) IsExclusion() bool {
+	return true
+}
+
+// FilterResults - remove records with field values from input list.
+// Empty input list means empty result, list of all records should be passed to find records of all data
+func (filter *MissingFilter) FilterResults(field *index.Field, inputKeys []int64) (result []int64, err error) {
+	if len(inputKeys) == 0 {
+		return make([]int64, 0, 0), err
+	}
+	return utils.DiffSortedInt(inputKeys, field.GetRecords().GetIds()), err
+}
+
+// FilterBitmap - remove records with field values from input bitmap.
+// Nil input means empty result, bitmap of all records should be passed to find records of all data
+func (filter *MissingFilter) FilterBitmap(field *index.Field, input *bitmap.Bitmap) (result *bitmap.Bitmap, err error) {
+	if input == nil {
+		return bitmap.New(), err
+	}
+	result = input.Clone()
+	result.AndNot(field.GetRecords().GetBitmap())
+	return result, err
+}
@@ -0,0 +1,62 @@
+package filter
+
+import (
+	"github.com/k-samuel/go-faceted-search/pkg/bitmap"
+	"github.com/k-samuel/go-faceted-search/pkg/index"
+	"github.com/k-samuel/go-faceted-search/pkg/utils"
+)
+
+// NotFilter - find records which do not match filter (composite filter)
+type NotFilter struct {
+	Filter FilterInterface
+}
+
+// GetFieldName - composite filter has no field
+func (filter *NotFilter) GetFieldName() string {
+	return ""
+}
+
+// FilterResults - composite filter can not be applied to the single field
+func (filter *NotFilter) FilterResults(field *index.Field, inputKeys []int64) (result []int64, err error) {
+	return make([]int64, 0, 0), ErrCompositeFilter
+}
+
+// FilterIndex - remove records matching filter, empty input list means all records
+func (filter *NotFilter) FilterIndex(idx *index.Index, inputKeys []int64) (result []int64, err error) {
+	result = allRecords(idx, inputKeys)
+	if filter.Filter == nil || len(result) == 0 {
+		return result, err
+	}
+	exclude, err := Apply(idx, filter.Filter, result)
+	if err != nil {
+		return make([]int64, 0, 0), err
+	}
+	return utils.DiffSortedInt(result, exclude), err
+}
+
+// FilterIndexBitmap - remove records matching filter using bitmaps, nil input means all records
+func (filter *NotFilter) FilterIndexBitmap(idx *index.Index, input *bitmap.Bitmap) (result *bitmap.Bitmap, err error) {
+	result = allBitmap(idx, input)
+	if filter.Filter == nil || result.IsEmpty() {
+		return result, err
+	}
+	exclude, err := ApplyBitmap(idx, filter.Filter, result)
+	if err != nil {
+		return bitmap.New(), err
+	}
+	result.AndNot(exclude)
+	return result, err
+}
+
+// HasField - check if filter has conditions for the field
+func (filter *NotFilter) HasField(fieldName string) bool {
+	return filter.Filter != nil && HasField(filter.Filter, fieldName)
+}
+
+// WithoutField - filter is dropped if it has conditions for the field
+func (filter *NotFilter) WithoutField(fieldName string) FilterInterface {
+	if filter.HasField(fieldName) {
+		return nil
+	}
+	return filter
+}
@@ -0,0 +1,70 @@
+package filter
+
+import (
+	"github.com/k-samuel/go-faceted-search/pkg/bitmap"
+	"github.com/k-samuel/go-faceted-search/pkg/index"
+	"github.com/k-samuel/go-faceted-search/pkg/utils"
+)
+
+// OrFilter - find records matching at least one of filters (composite filter)
+type OrFilter struct {
+	Filters []FilterInterface
+}
+
+// GetFieldName - composite filter has no field
+func (filter *OrFilter) GetFieldName() string {
+	return ""
+}
+
+// FilterResults - composite filter can not be applied to the single field
+func (filter *OrFilter) FilterResults(field *index.Field, inputKeys []int64) (result []int64, err error) {
+	return make([]int64, 0, 0), ErrCompositeFilter
+}
+
+// FilterIndex - find records matching at least one of filters, empty input list means all records
+func (filter *OrFilter) FilterIndex(idx *index.Index, inputKeys []int64) (result []int64, err error) {
+	result = make([]int64, 0)
+	for _, fl := range filter.Filters {
+		ids, err := Apply(idx, fl, inputKeys)
+		if err != nil {
+			return make([]int64, 0, 0), err
+		}
+		result = append(result, ids...)
+	}
+	if len(result) == 0 {
+		return result, err
+	}
+	return utils.Deduplicate(result), err
+}
+
+// FilterIndexBitmap - find records matching at least one of filters using bitmaps, nil input means all records
+func (filter *OrFilter) FilterIndexBitmap(idx *index.Index, input *bitmap.Bitmap) (result *bitmap.Bitmap, err error) {
+	result = bitmap.New()
+	for _, fl := range filter.Filters {
+		found, err := ApplyBitmap(idx, fl, input)
+		if err != nil {
+			return bitmap.New(), err
+		}
+		result.Or(found)
+	}
+	return result, err
+}
+
+// HasField - check if filter has conditions for the field
+func (filter *OrFilter) HasField(fieldName string) bool {
+	for _, fl := range filter.Filters {
+		if HasField(fl, fieldName) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithoutField - filter is dropped if any of alternatives has conditions for the field,
+// as removing only the alternative would make the filter stricter
+func (filter *OrFilter) WithoutField(fieldName string) FilterInterface {
+	if filter.HasField(fieldName) {
+		return nil
+	}
+	return filter
+}
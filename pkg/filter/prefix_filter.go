@@ -0,0 +1,40 @@
+package filter
+
+import (
+	"github.com/k-samuel/go-faceted-search/pkg/bitmap"
+	"github.com/k-samuel/go-faceted-search/pkg/index"
+)
+
+// PrefixFilter - filter facet data by field values which start with one of the prefixes ("A50" => "A500", "A50-1")
+type PrefixFilter struct {
+	FieldName string
+	Prefixes  []string
+}
+
+// GetFieldName - get field name
+func (filter *PrefixFilter) GetFieldName() string {
+	return filter.FieldName
+}
+
+// FilterResults - filter facet field data
+func (filter *PrefixFilter) FilterResults(field *index.Field, inputKeys []int64) (result []int64, err error) {
+	return unionList(filter.getValues(field), inputKeys), err
+}
+
+// FilterBitmap - filter facet field data using bitmaps
+func (filter *PrefixFilter) FilterBitmap(field *index.Field, input *bitmap.Bitmap) (result *bitmap.Bitmap, err error) {
+	return unionBitmap(filter.getValues(field), input), err
+}
+
+// getValues - get not empty field values which start with filter prefixes
+func (filter *PrefixFilter) getValues(field *index.Field) []*index.Value {
+	result := make([]*index.Value, 0, 10)
+	for _, prefix := range filter.Prefixes {
+		for _, name := range field.GetPrefixValues(field.Analyze(prefix)) {
+			if list := field.Values[name]; list.Count() > 0 {
+				result = append(result, list)
+			}
+		}
+	}
+	return result
+}
@@ -1,9 +1,10 @@
 package filter
 
 import (
+	"github.com/k-samuel/go-faceted-search/pkg/bitmap"
 	"github.com/k-samuel/go-faceted-search/pkg/index"
 	"github.com/k-samuel/go-faceted-search/pkg/utils"
-	"strconv"
+	"sort"
 )
 
 // RANGE_BOTH - range type with min and max value
@@ -20,6 +21,8 @@ type Range struct {
 	Min  float64
 	Max  float64
 	Type int
+	// ExcludeMax - Max is not included into range (Min <= value < Max), e.g. range of aggregation bucket
+	ExcludeMax bool
 }
 
 // RangeFilter filter facet data by field value range (numeric values)
@@ -40,25 +43,15 @@ func (filter *RangeFilter) FilterResults(field *index.Field, inputKeys []int64)
 		mapLen = 100
 	}
 
+	values, err := filter.getValues(field)
+	if err != nil {
+		return result, err
+	}
+
 	limitIds := make([]int64, 0, mapLen)
-	var value float64
 	// collect list for different values of one property
-	for val, valObject := range field.Values {
-		value, err = strconv.ParseFloat(val, 8)
-		if err != nil {
-			return result, err
-		}
-		if (filter.Values.Type == RANGE_BOTH || filter.Values.Type == RANGE_MIN) && value < filter.Values.Min {
-			continue
-		}
-
-		if (filter.Values.Type == RANGE_BOTH || filter.Values.Type == RANGE_MAX) && value > filter.Values.Max {
-			continue
-		}
-
-		for _, v := range valObject.Ids {
-			limitIds = append(limitIds, v)
-		}
+	for _, valObject := range values {
+		limitIds = append(limitIds, valObject.GetIds()...)
 	}
 
 	if len(limitIds) == 0 {
@@ -74,3 +67,39 @@ func (filter *RangeFilter) FilterResults(field *index.Field, inputKeys []int64)
 
 	return result, err
 }
+
+// FilterBitmap - filter facet field data using bitmaps
+func (filter *RangeFilter) FilterBitmap(field *index.Field, input *bitmap.Bitmap) (result *bitmap.Bitmap, err error) {
+	values, err := filter.getValues(field)
+	if err != nil {
+		return result, err
+	}
+	return unionBitmap(values, input), err
+}
+
+// getValues - get field values in range, non-numeric values are skipped
+func (filter *RangeFilter) getValues(field *index.Field) (result []*index.Value, err error) {
+	numbers := field.GetNumericValues()
+
+	start := 0
+	end := len(numbers)
+	if filter.Values.Type == RANGE_BOTH || filter.Values.Type == RANGE_MIN {
+		start = sort.Search(len(numbers), func(i int) bool { return numbers[i].Number >= filter.Values.Min })
+	}
+	if filter.Values.Type == RANGE_BOTH || filter.Values.Type == RANGE_MAX {
+		if filter.Values.ExcludeMax {
+			end = sort.Search(len(numbers), func(i int) bool { return numbers[i].Number >= filter.Values.Max })
+		} else {
+			end = sort.Search(len(numbers), func(i int) bool { return numbers[i].Number > filter.Values.Max })
+		}
+	}
+	if start >= end {
+		return make([]*index.Value, 0, 0), err
+	}
+
+	result = make([]*index.Value, 0, end-start)
+	for _, number := range numbers[start:end] {
+		result = append(result, field.Values[number.Name])
+	}
+	return result, err
+}
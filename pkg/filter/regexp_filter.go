@@ -0,0 +1,97 @@
+package filter
+
+import (
+	"github.com/k-samuel/go-faceted-search/pkg/bitmap"
+	"github.com/k-samuel/go-faceted-search/pkg/index"
+	"regexp"
+	"sync"
+)
+
+// RegexpFilter - filter facet data by field values which match regular expression (e.g. "^(5|10)W-").
+// Expression is matched against indexed (analyzed) values. Names of matched values are cached
+// by field version (up to regexpCacheSize versions, e.g. index and its snapshots), filter can be used by concurrent searches
+type RegexpFilter struct {
+	FieldName string
+	Regexp    *regexp.Regexp
+
+	mu sync.Mutex
+	// matched value names by field version
+	matched map[uint64][]string
+	// cached versions in order of adding
+	versions []uint64
+}
+
+// regexpCacheSize - max count of field versions cached by RegexpFilter
+const regexpCacheSize = 8
+
+// NewRegexpFilter - create filter with compiled expression
+func NewRegexpFilter(fieldName string, expr string) (*RegexpFilter, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &RegexpFilter{FieldName: fieldName, Regexp: re}, nil
+}
+
+// GetFieldName - get field name
+func (filter *RegexpFilter) GetFieldName() string {
+	return filter.FieldName
+}
+
+// FilterResults - filter facet field data
+func (filter *RegexpFilter) FilterResults(field *index.Field, inputKeys []int64) (result []int64, err error) {
+	return unionList(filter.getValues(field), inputKeys), err
+}
+
+// FilterBitmap - filter facet field data using bitmaps
+func (filter *RegexpFilter) FilterBitmap(field *index.Field, input *bitmap.Bitmap) (result *bitmap.Bitmap, err error) {
+	return unionBitmap(filter.getValues(field), input), err
+}
+
+// getValues - get not empty field values which match the expression
+func (filter *RegexpFilter) getValues(field *index.Field) []*index.Value {
+	names := filter.matchedNames(field)
+	result := make([]*index.Value, 0, len(names))
+	for _, name := range names {
+		if list, ok := field.Values[name]; ok && list.Count() > 0 {
+			result = append(result, list)
+		}
+	}
+	return result
+}
+
+// matchedNames - get names of field values which match the expression, cached by field version
+func (filter *RegexpFilter) matchedNames(field *index.Field) []string {
+	version := field.GetVersion()
+
+	filter.mu.Lock()
+	if names, ok := filter.matched[version]; ok {
+		filter.mu.Unlock()
+		return names
+	}
+	filter.mu.Unlock()
+
+	names := make([]string, 0, 10)
+	for name := range field.Values {
+		if filter.Regexp.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+
+	filter.mu.Lock()
+	defer filter.mu.Unlock()
+	if _, ok := filter.matched[version]; ok {
+		return names
+	}
+	if filter.matched == nil {
+		filter.matched = make(map[uint64][]string, regexpCacheSize)
+	}
+	// remove the oldest version
+	if len(filter.versions) >= regexpCacheSize {
+		delete(filter.matched, filter.versions[0])
+		filter.versions = filter.versions[1:]
+	}
+	filter.matched[version] = names
+	filter.versions = append(filter.versions, version)
+	return names
+}
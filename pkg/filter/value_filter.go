@@ -1,8 +1,8 @@
 package filter
 
 import (
+	"github.com/k-samuel/go-faceted-search/pkg/bitmap"
 	"github.com/k-samuel/go-faceted-search/pkg/index"
-	"github.com/k-samuel/go-faceted-search/pkg/utils"
 )
 
 // ValueFilter - filter facet data by field value
@@ -18,37 +18,26 @@ func (filter *ValueFilter) GetFieldName() string {
 
 // FilterResults - filter facet field data
 func (filter *ValueFilter) FilterResults(field *index.Field, inputKeys []int64) (result []int64, err error) {
+	return unionList(filter.getValues(field), inputKeys), err
+}
 
-	var list *index.Value
-	var mapLen = len(inputKeys)
-	var hasInput = true
-	if mapLen == 0 {
-		hasInput = false
-		mapLen = 100
-	}
-
-	result = make([]int64, 0, mapLen)
+// FilterBitmap - filter facet field data using bitmaps
+func (filter *ValueFilter) FilterBitmap(field *index.Field, input *bitmap.Bitmap) (result *bitmap.Bitmap, err error) {
+	return unionBitmap(filter.getValues(field), input), err
+}
 
-	// collect list of record id for different values of one field
+// getValues - get not empty field values of the filter
+func (filter *ValueFilter) getValues(field *index.Field) []*index.Value {
+	result := make([]*index.Value, 0, len(filter.Values))
 	for _, val := range filter.Values {
-
 		if !field.HasValue(val) {
 			continue
 		}
-
-		list = field.GetValue(val)
-		if len(list.Ids) == 0 {
+		list := field.GetValue(val)
+		if list.Count() == 0 {
 			continue
 		}
-
-		if hasInput {
-			result = append(result, utils.IntersectSortedInt(list.Ids, inputKeys)...)
-		} else {
-			result = append(result, list.Ids...)
-		}
-	}
-	if len(result) > 1 {
-		result = utils.Deduplicate(result)
+		result = append(result, list)
 	}
-	return result, err
+	return result
 }
@@ -0,0 +1,82 @@
+package filter
+
+import (
+	"github.com/k-samuel/go-faceted-search/pkg/bitmap"
+	"github.com/k-samuel/go-faceted-search/pkg/index"
+	"github.com/k-samuel/go-faceted-search/pkg/utils"
+)
+
+// ValueIntersectionFilter - filter facet data by field values, record should have all of the values
+// (AND condition for multi-value fields)
+type ValueIntersectionFilter struct {
+	FieldName string
+	Values    []string
+}
+
+// GetFieldName - get field name
+func (filter *ValueIntersectionFilter) GetFieldName() string {
+	return filter.FieldName
+}
+
+// FilterResults - filter facet field data
+func (filter *ValueIntersectionFilter) FilterResults(field *index.Field, inputKeys []int64) (result []int64, err error) {
+
+	if len(filter.Values) == 0 {
+		return make([]int64, 0, 0), err
+	}
+
+	// start value is inputKeys list, empty list means all records
+	result = inputKeys
+	hasInput := len(inputKeys) > 0
+
+	for _, val := range filter.Values {
+		if !field.HasValue(val) {
+			return make([]int64, 0, 0), err
+		}
+
+		ids := field.GetValue(val).GetIds()
+		if hasInput {
+			result = utils.IntersectSortedInt(ids, result)
+		} else {
+			result = make([]int64, len(ids))
+			copy(result, ids)
+			hasInput = true
+		}
+
+		if len(result) == 0 {
+			return result, err
+		}
+	}
+	return result, err
+}
+
+// FilterBitmap - filter facet field data using bitmaps
+func (filter *ValueIntersectionFilter) FilterBitmap(field *index.Field, input *bitmap.Bitmap) (result *bitmap.Bitmap, err error) {
+
+	if len(filter.Values) == 0 {
+		return bitmap.New(), err
+	}
+
+	for _, val := range filter.Values {
+		if !field.HasValue(val) {
+			return bitmap.New(), err
+		}
+
+		list := field.GetValue(val).GetBitmap()
+		switch {
+		case result != nil:
+			result.And(list)
+		case input != nil:
+			result = input.Clone()
+			result.And(list)
+		default:
+			// nil input means all records
+			result = list.Clone()
+		}
+
+		if result.IsEmpty() {
+			return result, err
+		}
+	}
+	return result, err
+}
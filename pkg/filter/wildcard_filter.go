@@ -0,0 +1,93 @@
+package filter
+
+import (
+	"github.com/k-samuel/go-faceted-search/pkg/bitmap"
+	"github.com/k-samuel/go-faceted-search/pkg/index"
+	"strings"
+	"unicode/utf8"
+)
+
+// WildcardFilter - filter facet data by glob-style patterns of field values:
+// "*" matches any sequence of characters, "?" matches a single character ("A5*23" => "A5023", "A5-X-23").
+// Values are looked up by the literal prefix of the pattern, so patterns which start with a wildcard check all values
+type WildcardFilter struct {
+	FieldName string
+	Patterns  []string
+}
+
+// GetFieldName - get field name
+func (filter *WildcardFilter) GetFieldName() string {
+	return filter.FieldName
+}
+
+// FilterResults - filter facet field data
+func (filter *WildcardFilter) FilterResults(field *index.Field, inputKeys []int64) (result []int64, err error) {
+	return unionList(filter.getValues(field), inputKeys), err
+}
+
+// FilterBitmap - filter facet field data using bitmaps
+func (filter *WildcardFilter) FilterBitmap(field *index.Field, input *bitmap.Bitmap) (result *bitmap.Bitmap, err error) {
+	return unionBitmap(filter.getValues(field), input), err
+}
+
+// getValues - get not empty field values which match filter patterns
+func (filter *WildcardFilter) getValues(field *index.Field) []*index.Value {
+	result := make([]*index.Value, 0, 10)
+	matched := make(map[string]struct{})
+	for _, pattern := range filter.Patterns {
+		pattern = field.Analyze(pattern)
+		prefix := pattern
+		if pos := strings.IndexAny(pattern, "*?"); pos >= 0 {
+			prefix = pattern[:pos]
+		}
+		for _, name := range field.GetPrefixValues(prefix) {
+			if _, ok := matched[name]; ok || !matchWildcard(pattern, name) {
+				continue
+			}
+			matched[name] = struct{}{}
+			if list := field.Values[name]; list.Count() > 0 {
+				result = append(result, list)
+			}
+		}
+	}
+	return result
+}
+
+// matchWildcard - check if value matches the pattern, "*" matches any sequence and "?" matches a single character
+func matchWildcard(pattern string, value string) bool {
+	// position of the last "*" in pattern and value position matched by it (backtracking point)
+	star, starValue := -1, 0
+	p, v := 0, 0
+	for v < len(value) {
+		if p < len(pattern) {
+			switch pattern[p] {
+			case '*':
+				star, starValue = p, v
+				p++
+				continue
+			case '?':
+				_, size := utf8.DecodeRuneInString(value[v:])
+				p++
+				v += size
+				continue
+			default:
+				if pattern[p] == value[v] {
+					p++
+					v++
+					continue
+				}
+			}
+		}
+		if star < 0 {
+			return false
+		}
+		// extend the sequence matched by the last "*" by one character
+		_, size := utf8.DecodeRuneInString(value[starValue:])
+		starValue += size
+		p, v = star+1, starValue
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
@@ -0,0 +1,116 @@
+package index
+
+import (
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+)
+
+// Analyzer - converts field values before indexing and lookup of filter values (trim, lowercase, synonyms ...)
+type Analyzer interface {
+	Analyze(value string) string
+}
+
+// AnalyzerFunc - function used as Analyzer
+type AnalyzerFunc func(value string) string
+
+// Analyze - convert value
+func (f AnalyzerFunc) Analyze(value string) string {
+	return f(value)
+}
+
+// TrimAnalyzer - remove leading and trailing white space
+var TrimAnalyzer Analyzer = AnalyzerFunc(strings.TrimSpace)
+
+// LowercaseAnalyzer - convert value to lower case
+var LowercaseAnalyzer Analyzer = AnalyzerFunc(strings.ToLower)
+
+// NFCAnalyzer - Unicode canonical composition, equal strings in different forms are the same value
+// ("e" + U+0301 => "é"). Values are not changed otherwise
+var NFCAnalyzer Analyzer = AnalyzerFunc(norm.NFC.String)
+
+// NFKCAnalyzer - Unicode compatibility composition: NFC plus replacement of compatibility characters
+// (fullwidth forms, ligatures, superscripts: "Ｃａｆé" => "Café", "ﬁ" => "fi")
+var NFKCAnalyzer Analyzer = AnalyzerFunc(norm.NFKC.String)
+
+// FoldAnalyzer - lossy accent folding of Latin letters: accented Latin-1 letters are converted into ASCII,
+// combining marks after Latin letters are removed, fullwidth ASCII forms are converted ("Ｃａｆé" => "Cafe").
+// Distinct values are merged ("résumé" and "resume" are the same value), combining marks of other scripts are kept.
+// Apply NFKCAnalyzer first to fold letters which are not included into Latin-1
+var FoldAnalyzer Analyzer = AnalyzerFunc(foldString)
+
+// chainAnalyzer - apply analyzers in order
+type chainAnalyzer []Analyzer
+
+func (chain chainAnalyzer) Analyze(value string) string {
+	for _, a := range chain {
+		value = a.Analyze(value)
+	}
+	return value
+}
+
+// NewChainAnalyzer - create analyzer which applies analyzers in order
+func NewChainAnalyzer(analyzers ...Analyzer) Analyzer {
+	return chainAnalyzer(analyzers)
+}
+
+// synonymAnalyzer - replace value with canonical one
+type synonymAnalyzer map[string]string
+
+func (synonyms synonymAnalyzer) Analyze(value string) string {
+	if result, ok := synonyms[value]; ok {
+		return result
+	}
+	return value
+}
+
+// NewSynonymAnalyzer - create analyzer which replaces values by map (synonym => canonical value).
+// Values are matched as is, so in a chain it should be placed after trim and lowercase analyzers
+func NewSynonymAnalyzer(synonyms map[string]string) Analyzer {
+	return synonymAnalyzer(synonyms)
+}
+
+// latin1Fold - ASCII replacement of accented Latin-1 letters
+var latin1Fold = map[rune]string{
+	'À': "A", 'Á': "A", 'Â': "A", 'Ã': "A", 'Ä': "A", 'Å': "A", 'Æ': "AE", 'Ç': "C",
+	'È': "E", 'É': "E", 'Ê': "E", 'Ë': "E", 'Ì': "I", 'Í': "I", 'Î': "I", 'Ï': "I",
+	'Ð': "D", 'Ñ': "N", 'Ò': "O", 'Ó': "O", 'Ô': "O", 'Õ': "O", 'Ö': "O", 'Ø': "O",
+	'Ù': "U", 'Ú': "U", 'Û': "U", 'Ü': "U", 'Ý': "Y", 'Þ': "TH", 'ß': "ss",
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'æ': "ae", 'ç': "c",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i",
+	'ð': "d", 'ñ': "n", 'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ý': "y", 'þ': "th", 'ÿ': "y",
+}
+
+func foldString(value string) string {
+	var b strings.Builder
+	b.Grow(len(value))
+	// base character of combining marks
+	var base rune
+	for _, r := range value {
+		if unicode.Is(unicode.Mn, r) {
+			// combining mark of Latin letter
+			if unicode.Is(unicode.Latin, base) {
+				continue
+			}
+			b.WriteRune(r)
+			continue
+		}
+		base = r
+		switch {
+		case r >= 0xFF01 && r <= 0xFF5E:
+			// fullwidth ASCII
+			b.WriteRune(r - 0xFF01 + '!')
+		case r == 0x3000:
+			// ideographic space
+			b.WriteByte(' ')
+		default:
+			if s, ok := latin1Fold[r]; ok {
+				b.WriteString(s)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package index
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Builder - accumulates index changes and publishes them as a new read-only snapshot on CommitChanges.
+// Searches bound to the published snapshot never observe partially applied changes
+type Builder struct {
+	index    *Index
+	snapshot atomic.Value
+	// keeps order of published snapshots
+	mu sync.Mutex
+}
+
+// NewBuilder - create builder for index changes, current index data is published as the first snapshot
+func NewBuilder(index *Index) *Builder {
+	var builder Builder
+	builder.index = index
+	builder.CommitChanges()
+	return &builder
+}
+
+// Add - add record to index, change is visible after CommitChanges
+func (builder *Builder) Add(id int64, record map[string]interface{}) error {
+	return builder.index.Add(id, record)
+}
+
+// Update - replace indexed record data, change is visible after CommitChanges
+func (builder *Builder) Update(id int64, record map[string]interface{}) error {
+	return builder.index.Update(id, record)
+}
+
+// Delete - remove record from index, change is visible after CommitChanges
+func (builder *Builder) Delete(id int64) error {
+	return builder.index.Delete(id)
+}
+
+// CommitChanges - save index changes and publish new snapshot
+func (builder *Builder) CommitChanges() {
+	builder.mu.Lock()
+	defer builder.mu.Unlock()
+
+	builder.snapshot.Store(builder.index.commitAndSnapshot())
+}
+
+// Snapshot - get last published read-only snapshot
+func (builder *Builder) Snapshot() *Index {
+	return builder.snapshot.Load().(*Index)
+}
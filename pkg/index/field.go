@@ -1,16 +1,50 @@
 package index
 
-import "sync"
+import (
+	"github.com/k-samuel/go-faceted-search/pkg/utils"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// fieldVersion - last version of field value lists, shared by all indexes
+var fieldVersion uint64
 
 // Field - struct to store value list for index field
 type Field struct {
-	mu     *sync.Mutex
-	Values map[string]*Value
+	storage int
+	// fieldType - type declared by index schema (FIELD_*)
+	fieldType int
+	analyzer  Analyzer
+	// display - the first indexed form of analyzed values which differs from the value name
+	display map[string]string
+	// generation of the index which owns the field (copy-on-write for snapshots)
+	generation uint64
+	// version of the field value list, unique for each change of value names (see GetVersion)
+	version uint64
+	// numeric values sorted by number, prepared by Index.CommitChanges and updated by Index.Update, Index.Delete
+	// (nil if values are added by Index.Add)
+	numeric []NumericValue
+	// records - id of records which have any value of the field (nil if it is not built yet)
+	records *Value
+	// value names sorted as strings, prepared and updated as numeric values
+	sorted []string
+	// ownCaches - numeric and sorted lists are not shared with snapshots and can be changed in place
+	ownCaches bool
+	Values    map[string]*Value
+}
+
+// NumericValue - field value which can be parsed as number
+type NumericValue struct {
+	Number float64
+	Name   string
 }
 
 // NewField - create field
 func NewField() *Field {
-	return &Field{Values: make(map[string]*Value, 100), mu: &sync.Mutex{}}
+	return &Field{Values: make(map[string]*Value, 100)}
 }
 
 // HasValues - check if field has any value
@@ -21,20 +55,334 @@ func (field *Field) HasValues() bool {
 	return false
 }
 
-// HasValue - check if field value exists
+// GetType - get field type declared by index schema (FIELD_AUTO if there is no declaration)
+func (field *Field) GetType() int {
+	return field.fieldType
+}
+
+// HasValue - check if field value exists. Values of typed fields are normalized ("07" => "7" for integer field)
 func (field *Field) HasValue(name string) bool {
-	_, ok := field.Values[name]
+	_, ok := field.Values[field.normalize(name)]
 	return ok
 }
 
+// normalize - convert filter value into stored representation of the field type and analyzer,
+// type conversion is skipped if value does not match the type
+func (field *Field) normalize(name string) string {
+	if field.fieldType != FIELD_AUTO && field.fieldType != FIELD_KEYWORD {
+		if result, err := formatValue(field.fieldType, name); err == nil {
+			name = result
+		}
+	}
+	if field.analyzer != nil {
+		name = field.analyzer.Analyze(name)
+	}
+	return name
+}
+
+// GetDisplayValue - get the first indexed form of analyzed value (value name if analyzer has not changed it)
+func (field *Field) GetDisplayValue(name string) string {
+	if display, ok := field.display[name]; ok {
+		return display
+	}
+	return name
+}
+
+// newValue - create empty value with field storage type
+func (field *Field) newValue() *Value {
+	if field.storage == STORAGE_BITMAP {
+		return NewBitmapValue()
+	}
+	return NewValue()
+}
+
 func (field *Field) createValue(name string) *Value {
-	field.mu.Lock()
-	field.Values[name] = NewValue()
-	field.mu.Unlock()
+	field.Values[name] = field.newValue()
+	field.Values[name].generation = field.generation
+	field.version = atomic.AddUint64(&fieldVersion, 1)
 	return field.Values[name]
 }
 
-// GetValue get field value by value string identifier
+// GetRecords - get id of records which have any value of the field (records without field value are missing).
+// The list is updated by index changes, it should not be changed
+func (field *Field) GetRecords() *Value {
+	if field.records != nil {
+		return field.records
+	}
+	return field.buildRecords()
+}
+
+// buildRecords - get union of value record lists, sorted lists are merged
+func (field *Field) buildRecords() *Value {
+	result := field.newValue()
+	if field.storage == STORAGE_BITMAP {
+		for _, value := range field.Values {
+			result.bitmap.Or(value.GetBitmap())
+		}
+		return result
+	}
+	lists := make([][]int64, 0, len(field.Values))
+	for _, value := range field.Values {
+		lists = append(lists, value.GetIds())
+	}
+	result.ids = utils.UnionSortedInt(lists)
+	return result
+}
+
+// writableRecords - get list of field records which can be changed, list shared with snapshot is copied
+func (field *Field) writableRecords() *Value {
+	if field.records == nil {
+		field.records = field.buildRecords()
+		field.records.generation = field.generation
+	} else if field.records.generation != field.generation {
+		field.records = field.records.clone(field.generation)
+	}
+	return field.records
+}
+
+// removeRecord - remove record id from list of field records
+func (field *Field) removeRecord(id int64) {
+	if field.records != nil && !field.records.hasId(id) {
+		return
+	}
+	field.writableRecords().removeId(id)
+}
+
+// writableValue - get value which can be changed, value shared with snapshot is copied.
+// Value is created if it does not exist
+func (field *Field) writableValue(name string) *Value {
+	value, ok := field.Values[name]
+	if !ok {
+		return field.createValue(name)
+	}
+	if value.generation != field.generation {
+		value = value.clone(field.generation)
+		field.Values[name] = value
+	}
+	return value
+}
+
+// clone - copy field for index generation, values are shared until change
+func (field *Field) clone(generation uint64) *Field {
+	result := &Field{
+		storage:    field.storage,
+		fieldType:  field.fieldType,
+		analyzer:   field.analyzer,
+		generation: generation,
+		version:    field.version,
+		numeric:    field.numeric,
+		sorted:     field.sorted,
+		records:    field.records,
+		Values:     make(map[string]*Value, len(field.Values)),
+	}
+	for name, value := range field.Values {
+		result.Values[name] = value
+	}
+	if field.display != nil {
+		result.display = make(map[string]string, len(field.display))
+		for name, display := range field.display {
+			result.display[name] = display
+		}
+	}
+	return result
+}
+
+func (field *Field) deleteValue(name string) {
+	delete(field.Values, name)
+	delete(field.display, name)
+	field.removeCaches(name)
+	field.version = atomic.AddUint64(&fieldVersion, 1)
+}
+
+// GetVersion - get version of field value names. Version is changed when value is created or deleted
+// and is unique across indexes, so fields with equal versions have the same value names (snapshots share versions).
+// It can be used to cache results computed from value names
+func (field *Field) GetVersion() uint64 {
+	return field.version
+}
+
+// Analyze - convert value by field analyzer without type conversion (prefixes and patterns of filters)
+func (field *Field) Analyze(name string) string {
+	if field.analyzer != nil {
+		return field.analyzer.Analyze(name)
+	}
+	return name
+}
+
+// GetSortedValues - get field value names sorted as strings.
+// The list is prepared by Index.CommitChanges, field with uncommitted changes builds the list on each call
+func (field *Field) GetSortedValues() []string {
+	if field.sorted != nil {
+		return field.sorted
+	}
+	return field.buildSorted()
+}
+
+// GetPrefixValues - get sorted field value names which start with prefix
+func (field *Field) GetPrefixValues(prefix string) []string {
+	names := field.GetSortedValues()
+	start := sort.SearchStrings(names, prefix)
+	end := start
+	for end < len(names) && strings.HasPrefix(names[end], prefix) {
+		end++
+	}
+	return names[start:end]
+}
+
+func (field *Field) buildSorted() []string {
+	result := make([]string, 0, len(field.Values))
+	for name := range field.Values {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// GetNumericValues - get numeric field values sorted by number, non-numeric values are skipped.
+// The list is prepared by Index.CommitChanges, field with uncommitted changes builds the list on each call
+func (field *Field) GetNumericValues() []NumericValue {
+	if field.numeric != nil {
+		return field.numeric
+	}
+	return field.buildNumeric()
+}
+
+// buildNumeric - parse field values and sort them by number, keyword fields have no numeric values
+func (field *Field) buildNumeric() []NumericValue {
+	if field.fieldType == FIELD_KEYWORD {
+		return make([]NumericValue, 0)
+	}
+	result := make([]NumericValue, 0, len(field.Values))
+	for name := range field.Values {
+		if number, ok := parseNumber(name); ok {
+			result = append(result, NumericValue{Number: number, Name: name})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return numericLess(result[i], result[j]) })
+	return result
+}
+
+// parseNumber - parse value name as number, NaN is not a number
+func parseNumber(name string) (float64, bool) {
+	number, err := strconv.ParseFloat(name, 64)
+	if err != nil || math.IsNaN(number) {
+		return 0, false
+	}
+	return number, true
+}
+
+// numericLess - order of numeric values: by number, then by name
+func numericLess(a, b NumericValue) bool {
+	if a.Number == b.Number {
+		return a.Name < b.Name
+	}
+	return a.Number < b.Number
+}
+
+// writableCaches - prepare numeric and sorted lists for change in place, lists shared with snapshots are copied
+func (field *Field) writableCaches() {
+	if field.ownCaches {
+		return
+	}
+	if field.numeric != nil {
+		field.numeric = append(make([]NumericValue, 0, len(field.numeric)+1), field.numeric...)
+	}
+	if field.sorted != nil {
+		field.sorted = append(make([]string, 0, len(field.sorted)+1), field.sorted...)
+	}
+	field.ownCaches = true
+}
+
+// insertCaches - add created value into prepared numeric and sorted lists
+func (field *Field) insertCaches(name string) {
+	if field.numeric == nil && field.sorted == nil {
+		return
+	}
+	field.writableCaches()
+	if field.sorted != nil {
+		pos := sort.SearchStrings(field.sorted, name)
+		field.sorted = append(field.sorted, "")
+		copy(field.sorted[pos+1:], field.sorted[pos:])
+		field.sorted[pos] = name
+	}
+	if number, ok := parseNumber(name); ok && field.numeric != nil && field.fieldType != FIELD_KEYWORD {
+		value := NumericValue{Number: number, Name: name}
+		pos := sort.Search(len(field.numeric), func(i int) bool { return !numericLess(field.numeric[i], value) })
+		field.numeric = append(field.numeric, NumericValue{})
+		copy(field.numeric[pos+1:], field.numeric[pos:])
+		field.numeric[pos] = value
+	}
+}
+
+// removeCaches - remove deleted value from prepared numeric and sorted lists
+func (field *Field) removeCaches(name string) {
+	if field.numeric == nil && field.sorted == nil {
+		return
+	}
+	field.writableCaches()
+	if field.sorted != nil {
+		pos := sort.SearchStrings(field.sorted, name)
+		if pos < len(field.sorted) && field.sorted[pos] == name {
+			field.sorted = append(field.sorted[:pos], field.sorted[pos+1:]...)
+		}
+	}
+	if number, ok := parseNumber(name); ok && field.numeric != nil {
+		value := NumericValue{Number: number, Name: name}
+		pos := sort.Search(len(field.numeric), func(i int) bool { return !numericLess(field.numeric[i], value) })
+		if pos < len(field.numeric) && field.numeric[pos] == value {
+			field.numeric = append(field.numeric[:pos], field.numeric[pos+1:]...)
+		}
+	}
+}
+
+// GetValue get field value by value string identifier, values of typed fields are normalized
 func (field *Field) GetValue(name string) *Value {
-	return field.Values[name]
+	return field.Values[field.normalize(name)]
+}
+
+// addRecord - add record id for field values
+func (field *Field) addRecord(id int64, values []recordValue, keepSorted bool) {
+	for _, v := range values {
+		if _, ok := field.Values[v.name]; !ok && v.name != v.display {
+			if field.display == nil {
+				field.display = make(map[string]string)
+			}
+			field.display[v.name] = v.display
+		}
+		field.addRecordValue(id, v.name, keepSorted)
+	}
+	if len(values) == 0 {
+		return
+	}
+	records := field.writableRecords()
+	if keepSorted {
+		records.insertId(id)
+	} else {
+		records.addId(id)
+	}
+}
+
+// addValue - add record with single value, used by Add of scalar values
+func (field *Field) addValue(id int64, name string) {
+	field.addRecordValue(id, name, false)
+	field.writableRecords().addId(id)
+}
+
+func (field *Field) addRecordValue(id int64, valString string, keepSorted bool) {
+	if _, ok := field.Values[valString]; !ok {
+		field.createValue(valString)
+		if keepSorted {
+			// Update keeps committed state of the field
+			field.insertCaches(valString)
+		} else {
+			field.numeric = nil
+			field.sorted = nil
+		}
+	}
+	value := field.writableValue(valString)
+	if keepSorted {
+		value.insertId(id)
+	} else {
+		value.addId(id)
+	}
 }
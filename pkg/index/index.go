@@ -1,10 +1,11 @@
 package index
 
 import (
-	"fmt"
+	"errors"
 	"sort"
-	"strconv"
 	"sync"
+
+	"github.com/k-samuel/go-faceted-search/pkg/bitmap"
 )
 
 /*
@@ -25,30 +26,143 @@ import (
  *   }
  */
 
-// Index - top level structure for facet data
+// STORAGE_LIST - record id of field value are stored as sorted list
+const STORAGE_LIST = 0
+
+// STORAGE_BITMAP - record id of field value are stored as compressed bitmap
+const STORAGE_BITMAP = 1
+
+// Options - index settings
+type Options struct {
+	// Storage - storage type of record id lists (STORAGE_LIST, STORAGE_BITMAP)
+	Storage int
+	// Schema - types of fields (optional), record values are validated and converted by Add
+	Schema Schema
+	// MaxDepth - max count of parts in names of nested fields ("features.color" has 2).
+	// Nested maps are indexed as dotted fields up to MaxDepth, deeper maps are flattened into values of the field.
+	// 0 or 1 - maps are flattened into values of the top level field
+	MaxDepth int
+	// Analyzers - analyzers of field values (optional), applied by Add and by lookup of filter values.
+	// The first indexed form of the value is kept as display value
+	Analyzers map[string]Analyzer
+}
+
+// Index - top level structure for facet data.
+// Changes (Add, Delete, Update, CommitChanges) are protected by write lock.
+// Readers which access fields and values directly should hold read lock (RLock, RUnlock),
+// search.Search and sorters do it for each call
 type Index struct {
-	fields map[string]*Field
-	mu     sync.Mutex
+	fields    map[string]*Field
+	mu        sync.RWMutex
+	storage   int
+	schema    Schema
+	maxDepth  int
+	analyzers map[string]Analyzer
+	// generation of index data, fields and values of previous generations are shared with snapshots
+	generation uint64
+	readOnly   bool
+	// recordFields - values of records (reverse index for Delete and Update),
+	// it is built by the first change which removes records and then updated by Add
+	recordFields map[int64][]fieldValue
 }
 
+// fieldValue - field value of record
+type fieldValue struct {
+	field string
+	value string
+}
+
+// ErrReadOnly - changes of index snapshot are not allowed
+var ErrReadOnly = errors.New("index snapshot is read-only")
+
 // NewIndex  - Index constructor
 func NewIndex() *Index {
+	return NewIndexWithOptions(Options{})
+}
+
+// NewIndexWithOptions - Index constructor with settings
+func NewIndexWithOptions(options Options) *Index {
 	var index Index
 	index.fields = make(map[string]*Field)
+	index.storage = options.Storage
+	index.schema = options.Schema
+	index.maxDepth = options.MaxDepth
+	index.analyzers = options.Analyzers
 	return &index
 }
 
+// RLock - lock index for reading
+func (index *Index) RLock() {
+	index.mu.RLock()
+}
+
+// RUnlock - unlock index locked for reading
+func (index *Index) RUnlock() {
+	index.mu.RUnlock()
+}
+
+// Snapshot - get read-only point-in-time copy of the index.
+// Snapshot shares data with the index, fields and values are copied by the index before the next change
+func (index *Index) Snapshot() *Index {
+	if index.readOnly {
+		return index
+	}
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	return index.snapshot()
+}
+
+// commitAndSnapshot - save changes and get snapshot of committed data,
+// other changes can not be applied between commit and snapshot
+func (index *Index) commitAndSnapshot() *Index {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	index.commit()
+	return index.snapshot()
+}
+
+func (index *Index) snapshot() *Index {
+	snapshot := &Index{
+		fields:     make(map[string]*Field, len(index.fields)),
+		storage:    index.storage,
+		schema:     index.schema,
+		maxDepth:   index.maxDepth,
+		analyzers:  index.analyzers,
+		generation: index.generation,
+		readOnly:   true,
+	}
+	for name, field := range index.fields {
+		snapshot.fields[name] = field
+	}
+	// current data belongs to the snapshot now
+	index.generation++
+	return snapshot
+}
+
+// IsReadOnly - check if index is a read-only snapshot
+func (index *Index) IsReadOnly() bool {
+	return index.readOnly
+}
+
+// IsBitmap - check if index stores record id lists as bitmaps (STORAGE_BITMAP)
+func (index *Index) IsBitmap() bool {
+	return index.storage == STORAGE_BITMAP
+}
+
 // GetIdList get all record id stored in index
 func (index *Index) GetIdList() []int64 {
+	if index.IsBitmap() {
+		return index.GetIdBitmap().ToArray()
+	}
 	data := make(map[int64]struct{}, 100)
 	result := make([]int64, 0, 100)
 	for _, f := range index.fields {
-		for _, v := range f.Values {
-			for _, id := range v.Ids {
-				if _, ok := data[id]; !ok {
-					data[id] = struct{}{}
-					result = append(result, id)
-				}
+		for _, id := range f.GetRecords().ids {
+			if _, ok := data[id]; !ok {
+				data[id] = struct{}{}
+				result = append(result, id)
 			}
 		}
 	}
@@ -56,15 +170,233 @@ func (index *Index) GetIdList() []int64 {
 	return result
 }
 
+// GetIdBitmap get bitmap of all record id stored in index
+func (index *Index) GetIdBitmap() *bitmap.Bitmap {
+	result := bitmap.New()
+	for _, f := range index.fields {
+		result.Or(f.GetRecords().GetBitmap())
+	}
+	return result
+}
+
 // GetFields get fields map
 func (index *Index) GetFields() map[string]*Field {
 	return index.fields
 }
 
-// Add - add record to index
-func (index *Index) Add(id int64, record map[string]interface{}) {
+// Add - add record to index. Record is not added if any value can not be indexed (*FieldError),
+// changes of snapshot return ErrReadOnly. Supported values: string, bool, numeric types, json.Number, time.Time,
+// fmt.Stringer, nil (missing value), maps and arrays ([]interface{}) of them
+func (index *Index) Add(id int64, record map[string]interface{}) error {
+	if index.readOnly {
+		return ErrReadOnly
+	}
+	if scalars, ok := index.scalarValues(record); ok {
+		index.mu.Lock()
+		defer index.mu.Unlock()
+
+		for _, v := range scalars {
+			index.writableField(v.field).addValue(id, v.value)
+		}
+		if index.recordFields != nil {
+			index.recordFields[id] = append(index.recordFields[id], scalars...)
+		}
+		return nil
+	}
+	values, err := index.recordValues(record)
+	if err != nil {
+		return err
+	}
+
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	for key, list := range values {
+		index.writableField(key).addRecord(id, list, false)
+	}
+	index.addRecordFields(id, values)
+	return nil
+}
+
+// scalarValues - fast path of Add: convert string and numeric values of record without schema and analyzers,
+// ok is false if values need recordValues (schema, analyzers, nested or other types)
+func (index *Index) scalarValues(record map[string]interface{}) ([]fieldValue, bool) {
+	if len(index.schema) > 0 || len(index.analyzers) > 0 {
+		return nil, false
+	}
+	result := make([]fieldValue, 0, len(record))
+	for key, val := range record {
+		// nil is a missing value
+		if val == nil {
+			continue
+		}
+		str, ok := scalarString(val)
+		if !ok {
+			return nil, false
+		}
+		result = append(result, fieldValue{field: key, value: str})
+	}
+	return result, true
+}
+
+// addRecordFields - add record values into reverse index (if it is built)
+func (index *Index) addRecordFields(id int64, values map[string][]recordValue) {
+	if index.recordFields == nil {
+		return
+	}
+	list := index.recordFields[id]
+	for key, fieldValues := range values {
+		for _, v := range fieldValues {
+			list = append(list, fieldValue{field: key, value: v.name})
+		}
+	}
+	index.recordFields[id] = list
+}
+
+// buildRecordFields - build reverse index of record values
+func (index *Index) buildRecordFields() {
+	index.recordFields = make(map[int64][]fieldValue)
+	for name, field := range index.fields {
+		for valName, value := range field.Values {
+			for _, id := range value.GetIds() {
+				index.recordFields[id] = append(index.recordFields[id], fieldValue{field: name, value: valName})
+			}
+		}
+	}
+}
+
+// Delete - remove record from index. Values and fields left without records are removed too,
+// changes of snapshot return ErrReadOnly. The first Delete (or Update) builds reverse index of record values,
+// so next changes process only values of the record
+func (index *Index) Delete(id int64) error {
+	if index.readOnly {
+		return ErrReadOnly
+	}
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	index.delete(id)
+	return nil
+}
+
+func (index *Index) delete(id int64) {
+	if index.recordFields == nil {
+		index.buildRecordFields()
+	}
+	for _, v := range index.recordFields[id] {
+		field, ok := index.fields[v.field]
+		if !ok || field.Values[v.value] == nil {
+			continue
+		}
+		writable := index.writableField(v.field)
+		value := writable.writableValue(v.value)
+		if !value.removeId(id) {
+			continue
+		}
+		if value.Count() == 0 {
+			writable.deleteValue(v.value)
+		}
+		writable.removeRecord(id)
+		if !writable.HasValues() {
+			index.deleteField(v.field)
+		}
+	}
+	delete(index.recordFields, id)
+}
+
+// Update - replace indexed record data. Sorted order of record id lists, numeric and sorted lists of field values
+// are updated in place, so there is no need to call CommitChanges after update of the committed index.
+// Record is not changed if any value can not be indexed (*FieldError)
+func (index *Index) Update(id int64, record map[string]interface{}) error {
+	if index.readOnly {
+		return ErrReadOnly
+	}
+	values, err := index.recordValues(record)
+	if err != nil {
+		return err
+	}
+
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	index.delete(id)
+	for key, list := range values {
+		index.writableField(key).addRecord(id, list, true)
+	}
+	index.addRecordFields(id, values)
+	return nil
+}
+
+// GetFieldType - get field type declared by schema (FIELD_AUTO if there is no declaration)
+func (index *Index) GetFieldType(name string) int {
+	return index.schema[name]
+}
+
+// recordValue - indexed form of record value and the original one
+type recordValue struct {
+	name    string
+	display string
+}
+
+// recordValues - convert record values into strings using schema and analyzers, nil values are skipped
+func (index *Index) recordValues(record map[string]interface{}) (map[string][]recordValue, error) {
+	fields := make(map[string][]interface{}, len(record))
 	for key, val := range record {
-		index.addValue(id, key, val)
+		index.collectValues(key, val, 1, fields)
+	}
+
+	result := make(map[string][]recordValue, len(fields))
+	for key, list := range fields {
+		fieldType := index.schema[key]
+		analyzer := index.analyzers[key]
+		values := make([]recordValue, 0, len(list))
+		for _, v := range list {
+			// nil is a missing value
+			if v == nil {
+				continue
+			}
+			str, err := formatValue(fieldType, v)
+			if err != nil {
+				return nil, &FieldError{Field: key, Value: v, Err: err}
+			}
+			value := recordValue{name: str, display: str}
+			if analyzer != nil {
+				value.name = analyzer.Analyze(str)
+			}
+			values = append(values, value)
+		}
+		if len(values) > 0 {
+			result[key] = values
+		}
+	}
+	return result, nil
+}
+
+// collectValues - collect scalar values of fields. Nested maps (and arrays of maps) are collected
+// as dotted fields up to max depth, arrays are collected as list of field values
+func (index *Index) collectValues(key string, val interface{}, depth int, result map[string][]interface{}) {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		if depth >= index.maxDepth {
+			// flatten map values into the field
+			for _, item := range v {
+				index.collectValues(key, item, depth, result)
+			}
+			return
+		}
+		for name, item := range v {
+			index.collectValues(key+"."+name, item, depth+1, result)
+		}
+	case []interface{}:
+		for _, item := range v {
+			index.collectValues(key, item, depth, result)
+		}
+	case []map[string]interface{}:
+		for _, item := range v {
+			index.collectValues(key, item, depth, result)
+		}
+	default:
+		result[key] = append(result[key], v)
 	}
 }
 
@@ -81,107 +413,78 @@ func (index *Index) GetRecordsCount(name, value string) int {
 		return 0
 	}
 	fld := index.fields[name]
-	if _, ok := fld.Values[value]; !ok {
+	if !fld.HasValue(value) {
 		return 0
 	}
-	return len(fld.Values[value].Ids)
+	return fld.GetValue(value).Count()
 }
 
 func (index *Index) createField(name string) *Field {
-	index.mu.Lock()
-	index.fields[name] = NewField()
-	index.mu.Unlock()
+	index.fields[name] = index.newField(name)
+	index.fields[name].generation = index.generation
 	return index.fields[name]
 }
 
+// writableField - get field which can be changed, field shared with snapshot is copied
+func (index *Index) writableField(name string) *Field {
+	field, ok := index.fields[name]
+	if !ok {
+		return index.createField(name)
+	}
+	if field.generation != index.generation {
+		field = field.clone(index.generation)
+		index.fields[name] = field
+	}
+	return field
+}
+
+// newField - create field with index storage type and schema type
+func (index *Index) newField(name string) *Field {
+	field := NewField()
+	field.storage = index.storage
+	field.fieldType = index.schema[name]
+	field.analyzer = index.analyzers[name]
+	return field
+}
+
+func (index *Index) deleteField(name string) {
+	delete(index.fields, name)
+}
+
 // GetField - get field struct from index
 func (index *Index) GetField(name string) *Field {
 	return index.fields[name]
 }
 
-// CommitChanges - save index changes
+// CommitChanges - save index changes: sort record id lists, prepare numeric and sorted values, lists of records of changed fields.
+// Snapshot has no changes to commit
 func (index *Index) CommitChanges() {
-	for _, f := range index.fields {
-		for _, v := range f.Values {
-			sort.Slice(v.Ids, func(i, j int) bool { return v.Ids[i] < v.Ids[j] })
-		}
-	}
-}
-
-func (index *Index) addValue(id int64, key string, val interface{}) {
-	var field *Field
-
-	if !index.HasField(key) {
-		field = index.createField(key)
-	} else {
-		field = index.GetField(key)
+	if index.readOnly {
+		return
 	}
+	index.mu.Lock()
+	defer index.mu.Unlock()
 
-	var valString string
-	var value *Value
+	index.commit()
+}
 
-	// map
-	if s, ok := val.(map[string]interface{}); ok {
-		for _, v := range s {
-			valString = getValueString(v)
-			if !field.HasValue(valString) {
-				value = field.createValue(valString)
-			} else {
-				value = field.GetValue(valString)
+func (index *Index) commit() {
+	for name, f := range index.fields {
+		for valName, v := range f.Values {
+			if !v.sorted {
+				index.writableField(name).writableValue(valName).sortIds()
 			}
-			value.addId(id)
 		}
-		return
-	}
-	// array
-	if s, ok := val.([]interface{}); ok {
-		for _, v := range s {
-			valString = getValueString(v)
-			if !field.HasValue(valString) {
-				value = field.createValue(valString)
-			} else {
-				value = field.GetValue(valString)
-			}
-			value.addId(id)
+		if f.numeric == nil {
+			field := index.writableField(name)
+			field.numeric = field.buildNumeric()
 		}
-		return
-	}
-	/// string
-	valString = getValueString(val)
-	if !field.HasValue(valString) {
-		value = field.createValue(valString)
-	} else {
-		value = field.GetValue(valString)
-	}
-	value.addId(id)
-}
-
-// getValueString - convert value to string
-func getValueString(val interface{}) string {
-
-	if s, ok := val.(bool); ok {
-		if s {
-			return "1"
+		if f.records == nil || !f.records.sorted {
+			index.writableField(name).writableRecords().sortIds()
+		}
+		if f.sorted == nil {
+			field := index.writableField(name)
+			field.sorted = field.buildSorted()
 		}
-		return "0"
-	}
-
-	if s, ok := val.(string); ok {
-		return s
-	}
-
-	if s, ok := val.(int64); ok {
-		return strconv.FormatInt(s, 10)
-	}
-
-	if s, ok := val.(int); ok {
-		return strconv.Itoa(s)
-	}
-
-	if s, ok := val.(float64); ok {
-		return strconv.FormatFloat(s, 'f', -1, 64)
 	}
-
-	fmt.Printf("undefined value type %T->%q\n", val, val)
-	panic("undefined value type")
 }
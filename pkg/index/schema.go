@@ -0,0 +1,191 @@
+package index
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// FIELD_AUTO - field type is not declared, values are converted into strings
+const FIELD_AUTO = 0
+
+// FIELD_KEYWORD - string values, values are not used as numbers by range filters and aggregations
+const FIELD_KEYWORD = 1
+
+// FIELD_INTEGER - integer values
+const FIELD_INTEGER = 2
+
+// FIELD_FLOAT - floating point values
+const FIELD_FLOAT = 3
+
+// FIELD_BOOL - boolean values stored as "1" and "0"
+const FIELD_BOOL = 4
+
+// FIELD_DATE - time.Time values or strings in RFC 3339 or "2006-01-02" format, stored as Unix time in seconds
+const FIELD_DATE = 5
+
+// Schema - types of index fields (field name => FIELD_* type), fields without type are FIELD_AUTO
+type Schema map[string]int
+
+// ErrInvalidValue - record value can not be indexed (unsupported type or value does not match field type)
+var ErrInvalidValue = errors.New("invalid field value")
+
+// FieldError - record value of the field can not be indexed, errors.Is(err, ErrInvalidValue) is true
+type FieldError struct {
+	Field string
+	Value interface{}
+	Err   error
+}
+
+// Error - error message with field name
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("field %q: %v", e.Field, e.Err)
+}
+
+// Unwrap - get cause of the error
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// IsNumeric - check if field type has numeric values
+func IsNumeric(fieldType int) bool {
+	return fieldType == FIELD_INTEGER || fieldType == FIELD_FLOAT || fieldType == FIELD_DATE
+}
+
+// formatValue - convert record value into string representation of the field type
+func formatValue(fieldType int, val interface{}) (string, error) {
+	switch fieldType {
+	case FIELD_INTEGER:
+		return formatInteger(val)
+	case FIELD_FLOAT:
+		return formatFloat(val)
+	case FIELD_BOOL:
+		return formatBool(val)
+	case FIELD_DATE:
+		return formatDate(val)
+	}
+	return getValueString(val)
+}
+
+// getValueString - convert value to string (FIELD_AUTO and FIELD_KEYWORD fields)
+func getValueString(val interface{}) (string, error) {
+	if v, ok := val.(bool); ok {
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	}
+	if v, ok := scalarString(val); ok {
+		return v, nil
+	}
+	switch v := val.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	}
+	return "", fmt.Errorf("%w: unsupported type %T", ErrInvalidValue, val)
+}
+
+// scalarString - convert string, numeric types and json.Number into string
+func scalarString(val interface{}) (string, bool) {
+	switch v := val.(type) {
+	case string:
+		return v, true
+	case int:
+		return strconv.Itoa(v), true
+	case int8:
+		return strconv.FormatInt(int64(v), 10), true
+	case int16:
+		return strconv.FormatInt(int64(v), 10), true
+	case int32:
+		return strconv.FormatInt(int64(v), 10), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), true
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10), true
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10), true
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return strconv.FormatInt(n, 10), true
+		}
+		if n, err := v.Float64(); err == nil {
+			return strconv.FormatFloat(n, 'f', -1, 64), true
+		}
+		return v.String(), true
+	}
+	return "", false
+}
+
+func formatInteger(val interface{}) (string, error) {
+	if str, ok := scalarString(val); ok {
+		if n, err := strconv.ParseInt(str, 10, 64); err == nil {
+			return strconv.FormatInt(n, 10), nil
+		}
+		if n, err := strconv.ParseFloat(str, 64); err == nil && n == math.Trunc(n) && math.Abs(n) < math.MaxInt64 {
+			return strconv.FormatInt(int64(n), 10), nil
+		}
+	}
+	return "", typeError("integer", val)
+}
+
+func formatFloat(val interface{}) (string, error) {
+	if str, ok := scalarString(val); ok {
+		if n, err := strconv.ParseFloat(str, 64); err == nil && !math.IsNaN(n) {
+			return strconv.FormatFloat(n, 'f', -1, 64), nil
+		}
+	}
+	return "", typeError("float", val)
+}
+
+func formatBool(val interface{}) (string, error) {
+	if v, ok := val.(bool); ok {
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	}
+	if str, ok := scalarString(val); ok {
+		if b, err := strconv.ParseBool(str); err == nil {
+			return formatBool(b)
+		}
+	}
+	return "", typeError("bool", val)
+}
+
+func formatDate(val interface{}) (string, error) {
+	if v, ok := val.(time.Time); ok {
+		return strconv.FormatInt(v.Unix(), 10), nil
+	}
+	if v, ok := val.(string); ok {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return strconv.FormatInt(t.Unix(), 10), nil
+		}
+		if t, err := time.Parse("2006-01-02", v); err == nil {
+			return strconv.FormatInt(t.Unix(), 10), nil
+		}
+	}
+	// Unix time
+	if result, err := formatInteger(val); err == nil {
+		return result, nil
+	}
+	return "", typeError("date", val)
+}
+
+func typeError(expected string, val interface{}) error {
+	return fmt.Errorf("%w: %s expected, got %T %v", ErrInvalidValue, expected, val, val)
+}
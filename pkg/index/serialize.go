@@ -0,0 +1,298 @@
+package index
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+	"sort"
+	"sync/atomic"
+
+	"github.com/k-samuel/go-faceted-search/pkg/bitmap"
+)
+
+/*
+ *  Binary format
+ *   magic     [4]byte  "GOFS"
+ *   version   uint16   (big endian)
+ *   fields    uvarint  fields count
+ *     name    uvarint length + bytes
+ *     values  uvarint values count
+ *       name  uvarint length + bytes
+ *       display uvarint length + bytes, display form of analyzed value (empty if it is the value name, since version 2)
+ *       ids   uvarint ids count, first id as varint, next ids as uvarint delta from previous one
+ *   checksum  uint32   CRC32 (IEEE) of all previous bytes (big endian)
+ */
+
+// FORMAT_VERSION - current version of index binary format
+const FORMAT_VERSION uint16 = 2
+
+// formatVersionNoDisplay - version of binary format without display values of fields, supported for reading
+const formatVersionNoDisplay uint16 = 1
+
+var formatMagic = []byte("GOFS")
+
+// ErrInvalidFormat - data is not an index binary snapshot
+var ErrInvalidFormat = errors.New("invalid index format")
+
+// ErrUnsupportedVersion - index binary format version is not supported
+var ErrUnsupportedVersion = errors.New("unsupported index format version")
+
+// ErrChecksum - index binary data is corrupted
+var ErrChecksum = errors.New("index checksum mismatch")
+
+// preallocation limit for lists read from untrusted input
+const maxPrealloc = 1 << 16
+
+// WriteTo - write index into binary format. Implements io.WriterTo
+func (index *Index) WriteTo(w io.Writer) (n int64, err error) {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+
+	enc := &encoder{w: bufio.NewWriter(w), crc: crc32.NewIEEE()}
+
+	enc.write(formatMagic)
+	var ver [2]byte
+	binary.BigEndian.PutUint16(ver[:], FORMAT_VERSION)
+	enc.write(ver[:])
+
+	fieldNames := make([]string, 0, len(index.fields))
+	for name := range index.fields {
+		fieldNames = append(fieldNames, name)
+	}
+	sort.Strings(fieldNames)
+
+	enc.uvarint(uint64(len(fieldNames)))
+	for _, name := range fieldNames {
+		field := index.fields[name]
+		enc.string(name)
+
+		valueNames := make([]string, 0, len(field.Values))
+		for val := range field.Values {
+			valueNames = append(valueNames, val)
+		}
+		sort.Strings(valueNames)
+
+		enc.uvarint(uint64(len(valueNames)))
+		for _, val := range valueNames {
+			enc.string(val)
+			enc.string(field.display[val])
+			enc.ids(field.Values[val].GetIds())
+		}
+	}
+
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], enc.crc.Sum32())
+	enc.write(sum[:])
+
+	if enc.err == nil {
+		enc.err = enc.w.Flush()
+	}
+	return enc.n, enc.err
+}
+
+// ReadFrom - create index from binary data written by Index.WriteTo
+func ReadFrom(r io.Reader) (*Index, error) {
+	return ReadFromWithOptions(r, Options{})
+}
+
+// ReadFromWithOptions - create index with options from binary data written by Index.WriteTo,
+// schema and analyzers are not stored in binary data and should be passed in options
+func ReadFromWithOptions(r io.Reader, options Options) (*Index, error) {
+	dec := &decoder{r: bufio.NewReader(r), crc: crc32.NewIEEE()}
+
+	magic := dec.bytes(uint64(len(formatMagic)))
+	if dec.err != nil || string(magic) != string(formatMagic) {
+		return nil, ErrInvalidFormat
+	}
+	ver := dec.bytes(2)
+	if dec.err != nil {
+		return nil, ErrInvalidFormat
+	}
+	version := binary.BigEndian.Uint16(ver)
+	if version != FORMAT_VERSION && version != formatVersionNoDisplay {
+		return nil, ErrUnsupportedVersion
+	}
+
+	index := NewIndexWithOptions(options)
+
+	fieldsCount := dec.uvarint()
+	for i := uint64(0); i < fieldsCount && dec.err == nil; i++ {
+		name := dec.string()
+		valuesCount := dec.uvarint()
+		field := index.newField(name)
+		for j := uint64(0); j < valuesCount && dec.err == nil; j++ {
+			val := dec.string()
+			if version != formatVersionNoDisplay {
+				if display := dec.string(); display != "" {
+					if field.display == nil {
+						field.display = make(map[string]string)
+					}
+					field.display[val] = display
+				}
+			}
+			ids := dec.ids()
+			if field.storage == STORAGE_BITMAP {
+				field.Values[val] = &Value{bitmap: bitmap.FromIds(ids), sorted: true}
+			} else {
+				field.Values[val] = &Value{ids: ids, sorted: true}
+			}
+		}
+		field.version = atomic.AddUint64(&fieldVersion, 1)
+		index.fields[name] = field
+	}
+	if dec.err != nil {
+		return nil, dec.err
+	}
+
+	sum := dec.crc.Sum32()
+	var stored [4]byte
+	if _, err := io.ReadFull(dec.r, stored[:]); err != nil {
+		return nil, ErrInvalidFormat
+	}
+	if binary.BigEndian.Uint32(stored[:]) != sum {
+		return nil, ErrChecksum
+	}
+	index.commit()
+	return index, nil
+}
+
+func capacity(count uint64) int {
+	if count > maxPrealloc {
+		return maxPrealloc
+	}
+	return int(count)
+}
+
+// encoder - binary writer which keeps first error, count of written bytes and checksum
+type encoder struct {
+	w   *bufio.Writer
+	crc hash.Hash32
+	n   int64
+	err error
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (enc *encoder) write(p []byte) {
+	if enc.err != nil {
+		return
+	}
+	var l int
+	l, enc.err = enc.w.Write(p)
+	enc.n += int64(l)
+	enc.crc.Write(p[:l])
+}
+
+func (enc *encoder) uvarint(v uint64) {
+	l := binary.PutUvarint(enc.buf[:], v)
+	enc.write(enc.buf[:l])
+}
+
+func (enc *encoder) varint(v int64) {
+	l := binary.PutVarint(enc.buf[:], v)
+	enc.write(enc.buf[:l])
+}
+
+func (enc *encoder) string(s string) {
+	enc.uvarint(uint64(len(s)))
+	enc.write([]byte(s))
+}
+
+func (enc *encoder) ids(ids []int64) {
+	enc.uvarint(uint64(len(ids)))
+	if len(ids) == 0 {
+		return
+	}
+	enc.varint(ids[0])
+	for i := 1; i < len(ids); i++ {
+		enc.uvarint(uint64(ids[i] - ids[i-1]))
+	}
+}
+
+// decoder - binary reader which keeps first error and checksum of read bytes
+type decoder struct {
+	r   *bufio.Reader
+	crc hash.Hash32
+	err error
+}
+
+func (dec *decoder) ReadByte() (byte, error) {
+	b, err := dec.r.ReadByte()
+	if err == nil {
+		dec.crc.Write([]byte{b})
+	}
+	return b, err
+}
+
+func (dec *decoder) fail(err error) {
+	if dec.err != nil {
+		return
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = ErrInvalidFormat
+	}
+	dec.err = err
+}
+
+func (dec *decoder) bytes(l uint64) []byte {
+	if dec.err != nil {
+		return nil
+	}
+	if l > math.MaxInt32 {
+		dec.fail(ErrInvalidFormat)
+		return nil
+	}
+	var buf bytes.Buffer
+	buf.Grow(capacity(l))
+	if _, err := io.CopyN(&buf, dec.r, int64(l)); err != nil {
+		dec.fail(err)
+		return nil
+	}
+	dec.crc.Write(buf.Bytes())
+	return buf.Bytes()
+}
+
+func (dec *decoder) uvarint() uint64 {
+	if dec.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(dec)
+	if err != nil {
+		dec.fail(err)
+	}
+	return v
+}
+
+func (dec *decoder) varint() int64 {
+	if dec.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(dec)
+	if err != nil {
+		dec.fail(err)
+	}
+	return v
+}
+
+func (dec *decoder) string() string {
+	return string(dec.bytes(dec.uvarint()))
+}
+
+func (dec *decoder) ids() []int64 {
+	count := dec.uvarint()
+	ids := make([]int64, 0, capacity(count))
+	if count == 0 || dec.err != nil {
+		return ids
+	}
+	last := dec.varint()
+	ids = append(ids, last)
+	for i := uint64(1); i < count && dec.err == nil; i++ {
+		last += int64(dec.uvarint())
+		ids = append(ids, last)
+	}
+	return ids
+}
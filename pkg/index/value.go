@@ -1,21 +1,184 @@
 package index
 
-import "sync"
+import (
+	"sort"
+
+	"github.com/k-samuel/go-faceted-search/pkg/bitmap"
+)
 
 // Value - list of record id for value
 type Value struct {
-	mu  *sync.Mutex
-	Ids []int64
+	sorted bool
+	// generation of the index which owns the value (copy-on-write for snapshots)
+	generation uint64
+	// ids - list of record id (STORAGE_LIST), sorted after Index.CommitChanges. Use GetIds to read it
+	ids []int64
+	// bitmap of record id (STORAGE_BITMAP)
+	bitmap *bitmap.Bitmap
 }
 
 // NewValue - create value
 func NewValue() *Value {
-	return &Value{Ids: make([]int64, 0, 100), mu: &sync.Mutex{}}
+	return &Value{ids: make([]int64, 0, 100), sorted: true}
+}
+
+// NewBitmapValue - create value with bitmap storage of record id list
+func NewBitmapValue() *Value {
+	return &Value{bitmap: bitmap.New(), sorted: true}
+}
+
+// IsBitmap - check if value uses bitmap storage
+func (value *Value) IsBitmap() bool {
+	return value.bitmap != nil
+}
+
+// IsSorted - check if list of record id is sorted (there are no uncommitted changes of the list)
+func (value *Value) IsSorted() bool {
+	return value.sorted
+}
+
+// Count - get count of records with value
+func (value *Value) Count() int {
+	if value.bitmap != nil {
+		return value.bitmap.Cardinality()
+	}
+	return len(value.ids)
+}
+
+// GetIds - get sorted list of record id. List storage returns internal slice, it should not be changed
+func (value *Value) GetIds() []int64 {
+	if value.bitmap != nil {
+		return value.bitmap.ToArray()
+	}
+	return value.sortedIds()
+}
+
+// GetBitmap - get bitmap of record id. Bitmap storage returns internal bitmap, it should not be changed
+func (value *Value) GetBitmap() *bitmap.Bitmap {
+	if value.bitmap != nil {
+		return value.bitmap
+	}
+	return bitmap.FromIds(value.ids)
+}
+
+// clone - copy value for index generation
+func (value *Value) clone(generation uint64) *Value {
+	result := &Value{sorted: value.sorted, generation: generation}
+	if value.bitmap != nil {
+		result.bitmap = value.bitmap.Clone()
+	} else {
+		result.ids = make([]int64, len(value.ids))
+		copy(result.ids, value.ids)
+	}
+	return result
+}
+
+// hasId - check if value contains record id
+func (value *Value) hasId(id int64) bool {
+	if value.bitmap != nil {
+		return value.bitmap.Contains(id)
+	}
+	if value.sorted {
+		pos := sort.Search(len(value.ids), func(i int) bool { return value.ids[i] >= id })
+		return pos < len(value.ids) && value.ids[pos] == id
+	}
+	for _, v := range value.ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
 }
 
 // addId - add record id into value struct
 func (value *Value) addId(id int64) {
-	value.mu.Lock()
-	value.Ids = append(value.Ids, id)
-	value.mu.Unlock()
+	if value.bitmap != nil {
+		value.bitmap.Add(id)
+		return
+	}
+	n := len(value.ids)
+	// record values are added together, duplicate value of the record is the last one
+	if n > 0 && value.ids[n-1] == id {
+		return
+	}
+	if n > 0 && value.ids[n-1] > id {
+		value.sorted = false
+	}
+	value.ids = append(value.ids, id)
+}
+
+// insertId - add record id keeping sorted order of the list (if it is already sorted)
+func (value *Value) insertId(id int64) {
+	if value.bitmap != nil {
+		value.bitmap.Add(id)
+		return
+	}
+
+	n := len(value.ids)
+	if !value.sorted || n == 0 || value.ids[n-1] < id {
+		value.ids = append(value.ids, id)
+		return
+	}
+	pos := sort.Search(n, func(i int) bool { return value.ids[i] >= id })
+	if value.ids[pos] == id {
+		return
+	}
+	value.ids = append(value.ids, 0)
+	copy(value.ids[pos+1:], value.ids[pos:])
+	value.ids[pos] = id
+}
+
+// removeId - remove record id from value struct, returns true if id was found
+func (value *Value) removeId(id int64) bool {
+	if value.bitmap != nil {
+		return value.bitmap.Remove(id)
+	}
+
+	if value.sorted {
+		n := len(value.ids)
+		start := sort.Search(n, func(i int) bool { return value.ids[i] >= id })
+		end := start
+		for end < n && value.ids[end] == id {
+			end++
+		}
+		if start == end {
+			return false
+		}
+		value.ids = append(value.ids[:start], value.ids[end:]...)
+		return true
+	}
+
+	// unsorted list (changes are not committed yet)
+	found := false
+	j := 0
+	for _, v := range value.ids {
+		if v == id {
+			found = true
+			continue
+		}
+		value.ids[j] = v
+		j++
+	}
+	value.ids = value.ids[:j]
+	return found
+}
+
+// sortIds - sort list of record id
+func (value *Value) sortIds() {
+	if value.sorted {
+		return
+	}
+	sort.Slice(value.ids, func(i, j int) bool { return value.ids[i] < value.ids[j] })
+	value.sorted = true
+}
+
+// sortedIds - get sorted list of record id without changing the value
+func (value *Value) sortedIds() []int64 {
+	if value.sorted {
+		return value.ids
+	}
+	ids := make([]int64, len(value.ids))
+	copy(ids, value.ids)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
 }
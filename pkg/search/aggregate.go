@@ -0,0 +1,753 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/k-samuel/go-faceted-search/pkg/filter"
+	"github.com/k-samuel/go-faceted-search/pkg/index"
+	"math"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// AggregationQuery - aggregation settings
+type AggregationQuery struct {
+	// Filters - list of filters
+	Filters []filter.FilterInterface
+	// Records - limit aggregation using list of record id (optional)
+	Records []int64
+	// Ranges - fields aggregated by numeric ranges instead of values
+	Ranges map[string]*RangeAggregation
+	// Fields - list of fields to aggregate (optional, all index fields by default)
+	Fields []string
+	// Limits - max count of values for field, values with the largest records count are kept (optional)
+	Limits map[string]int
+	// SelfFiltering - apply field filters to the field aggregation (single-select facets),
+	// by default own filters are excluded (multi-select facets)
+	SelfFiltering bool
+	// SelfFilteringFields - list of fields which aggregation uses own filters (optional)
+	SelfFilteringFields []string
+	// Order - order of values in AggregateSorted results (ORDER_COUNT by default)
+	Order int
+	// FieldsOrder - order of values for fields (optional), overrides Order
+	FieldsOrder map[string]int
+	// DisplayValues - use the first indexed form of values changed by analyzers (index.Options.Analyzers)
+	// instead of analyzed ones
+	DisplayValues bool
+	// Missing - name of the value for count of records without field value ("not specified") in Aggregate results,
+	// Aggregate returns ErrMissingConflict if a value of found records has the same name.
+	// AggregateSorted sets the count as FieldAggregation.Missing (optional, records are not counted if empty)
+	Missing string
+}
+
+// ErrMissingConflict - name of the value for records without field value (AggregationQuery.Missing) is used by field value
+var ErrMissingConflict = errors.New("missing value name conflicts with field value")
+
+// RangeAggregation - aggregation of numeric field values by ranges.
+// Buckets are half-open (From <= value < To) so neighbour buckets do not overlap,
+// use Bucket.Filter to find records of the bucket
+type RangeAggregation struct {
+	// Buckets - list of ranges
+	Buckets []Bucket
+	// Interval - width of histogram buckets, used if there are no Buckets
+	Interval float64
+}
+
+// Bucket - range of numeric values From <= value < To (To is not included, see RangeAggregation)
+type Bucket struct {
+	From float64
+	To   float64
+	// Type - filter.RANGE_BOTH, filter.RANGE_MIN (only From limit), filter.RANGE_MAX (only To limit)
+	Type int
+	// Name - bucket name in aggregation results, default names are "From-To", "From+", "*-To"
+	Name string
+}
+
+// Filter - get filter which finds records counted in the bucket (To is not included)
+func (bucket *Bucket) Filter(fieldName string) *filter.RangeFilter {
+	return &filter.RangeFilter{
+		FieldName: fieldName,
+		Values:    filter.Range{Min: bucket.From, Max: bucket.To, Type: bucket.Type, ExcludeMax: true},
+	}
+}
+
+// GetName - get bucket name for aggregation results
+func (bucket *Bucket) GetName() string {
+	if bucket.Name != "" {
+		return bucket.Name
+	}
+	switch bucket.Type {
+	case filter.RANGE_MIN:
+		return formatNumber(bucket.From) + "+"
+	case filter.RANGE_MAX:
+		return "*-" + formatNumber(bucket.To)
+	}
+	return formatNumber(bucket.From) + "-" + formatNumber(bucket.To)
+}
+
+// Stats - statistics of numeric field values.
+// Record with several values is counted for each value
+type Stats struct {
+	Count int
+	Min   float64
+	Max   float64
+	Sum   float64
+	Avg   float64
+}
+
+// fieldResult - aggregation result of the field
+type fieldResult struct {
+	field string
+	data  interface{}
+}
+
+// aggregation - aggregation call data shared by workers
+type aggregation struct {
+	ctx     context.Context
+	search  *Search
+	query   *AggregationQuery
+	filters []filter.FilterInterface
+	records []int64
+	// records found by all filters
+	filtered *recordSet
+	// count of all index records, counted once for missing values
+	totalOnce  sync.Once
+	totalCount int
+}
+
+// AggregateFilters - find acceptable filter values
+func (search *Search) AggregateFilters(filters []filter.FilterInterface, inputRecords []int64) (result map[string]map[string]int, err error) {
+	return search.AggregateContext(context.Background(), &AggregationQuery{Filters: filters, Records: inputRecords})
+}
+
+// AggregateFiltersContext - find acceptable filter values, aggregation is stopped with ctx.Err() when context is done
+func (search *Search) AggregateFiltersContext(ctx context.Context, filters []filter.FilterInterface, inputRecords []int64) (result map[string]map[string]int, err error) {
+	return search.AggregateContext(ctx, &AggregationQuery{Filters: filters, Records: inputRecords})
+}
+
+// Aggregate - find acceptable filter values and count records for them
+func (search *Search) Aggregate(query *AggregationQuery) (result map[string]map[string]int, err error) {
+	return search.AggregateContext(context.Background(), query)
+}
+
+// AggregateContext - find acceptable filter values and count records for them,
+// aggregation is stopped with ctx.Err() when context is done
+func (search *Search) AggregateContext(ctx context.Context, query *AggregationQuery) (result map[string]map[string]int, err error) {
+	search.index.RLock()
+	defer search.index.RUnlock()
+
+	result = make(map[string]map[string]int)
+
+	agg, err := search.newAggregation(ctx, query)
+	if err != nil {
+		return result, err
+	}
+
+	data, err := search.aggregateFields(ctx, agg.fieldNames(), agg.countField)
+	if err != nil {
+		return result, err
+	}
+	for name, v := range data {
+		result[name] = v.(map[string]int)
+	}
+	return result, err
+}
+
+// AggregateStats - get statistics of numeric values for fields (count, min, max, sum, avg),
+// fields without numeric values in found records are omitted
+func (search *Search) AggregateStats(query *AggregationQuery, fields []string) (result map[string]*Stats, err error) {
+	return search.AggregateStatsContext(context.Background(), query, fields)
+}
+
+// AggregateStatsContext - get statistics of numeric values for fields, aggregation is stopped when context is done
+func (search *Search) AggregateStatsContext(ctx context.Context, query *AggregationQuery, fields []string) (result map[string]*Stats, err error) {
+	search.index.RLock()
+	defer search.index.RUnlock()
+
+	result = make(map[string]*Stats)
+
+	agg, err := search.newAggregation(ctx, query)
+	if err != nil {
+		return result, err
+	}
+
+	names := make([]string, 0, len(fields))
+	for _, name := range fields {
+		if search.index.HasField(name) {
+			names = append(names, name)
+		}
+	}
+
+	data, err := search.aggregateFields(ctx, names, agg.fieldStats)
+	if err != nil {
+		return result, err
+	}
+	for name, v := range data {
+		if stats := v.(*Stats); stats != nil {
+			result[name] = stats
+		}
+	}
+	return result, err
+}
+
+// AggregateValues - find acceptable filter values without counting records (faster than Aggregate),
+// Ranges and Limits of the query are not used. Values are sorted by name
+func (search *Search) AggregateValues(query *AggregationQuery) (result map[string][]string, err error) {
+	return search.AggregateValuesContext(context.Background(), query)
+}
+
+// AggregateValuesContext - find acceptable filter values without counting records, aggregation is stopped when context is done
+func (search *Search) AggregateValuesContext(ctx context.Context, query *AggregationQuery) (result map[string][]string, err error) {
+	search.index.RLock()
+	defer search.index.RUnlock()
+
+	result = make(map[string][]string)
+
+	agg, err := search.newAggregation(ctx, query)
+	if err != nil {
+		return result, err
+	}
+
+	data, err := search.aggregateFields(ctx, agg.fieldNames(), agg.availableValues)
+	if err != nil {
+		return result, err
+	}
+	for name, v := range data {
+		result[name] = v.([]string)
+	}
+	return result, err
+}
+
+// AggregateSorted - find acceptable filter values and count records for them,
+// values are listed in order of query.Order. If field has Limits, values with the largest records count are listed
+// and records which have only the rest values are counted as Other. Ranges are listed in order of buckets
+func (search *Search) AggregateSorted(query *AggregationQuery) (result map[string]*FieldAggregation, err error) {
+	return search.AggregateSortedContext(context.Background(), query)
+}
+
+// AggregateSortedContext - find acceptable filter values and get ordered lists of them, aggregation is stopped when context is done
+func (search *Search) AggregateSortedContext(ctx context.Context, query *AggregationQuery) (result map[string]*FieldAggregation, err error) {
+	search.index.RLock()
+	defer search.index.RUnlock()
+
+	result = make(map[string]*FieldAggregation)
+
+	agg, err := search.newAggregation(ctx, query)
+	if err != nil {
+		return result, err
+	}
+
+	data, err := search.aggregateFields(ctx, agg.fieldNames(), agg.sortedField)
+	if err != nil {
+		return result, err
+	}
+	for name, v := range data {
+		result[name] = v.(*FieldAggregation)
+	}
+	return result, err
+}
+
+// newAggregation - prepare filters and find records for aggregation
+func (search *Search) newAggregation(ctx context.Context, query *AggregationQuery) (agg *aggregation, err error) {
+	agg = &aggregation{ctx: ctx, search: search, query: query, filters: query.Filters, records: query.Records}
+
+	if len(agg.records) > 0 {
+		sort.Slice(agg.records, func(i, j int) bool { return agg.records[i] < agg.records[j] })
+	}
+
+	// Aggregates optimisation for value filters.
+	// The fewer elements after the first filtering, the fewer data copies and memory allocations in iterations
+	if len(agg.records) == 0 && len(agg.filters) > 1 {
+		agg.filters = search.sortFilters(agg.filters)
+	}
+
+	if len(agg.filters) > 0 || len(agg.records) > 0 {
+		agg.filtered, err = search.findRecords(ctx, agg.filters, agg.records)
+	}
+	return agg, err
+}
+
+// aggregateFields - aggregate fields in goroutines
+func (search *Search) aggregateFields(ctx context.Context, fields []string, aggregate func(fieldName string) (interface{}, error)) (map[string]interface{}, error) {
+
+	if err := ctx.Err(); err != nil {
+		return make(map[string]interface{}), err
+	}
+
+	workers := search.workers(len(fields))
+	if workers == 1 {
+		return search.aggregateSequential(ctx, fields, aggregate)
+	}
+
+	// Create a cancel context for stopping on error
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	wg := &sync.WaitGroup{}
+	in := make(chan string, len(fields))
+	out := make(chan *fieldResult, 10)
+	errChan := make(chan error)
+
+	// aggregate fields in goroutines
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go aggregateWorker(ctx, in, out, errChan, wg, search.options.Pool, aggregate)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	// send fields into aggregation queue
+	for _, name := range fields {
+		in <- name
+	}
+	close(in)
+
+	// collect aggregation results
+	result := make(map[string]interface{}, len(fields))
+	for {
+		select {
+		case err := <-errChan:
+			// send cancel to goroutines on field aggregation error
+			// no need to process full result
+			cancel()
+			// wait for goroutines stopped
+			wg.Wait()
+			return make(map[string]interface{}), err
+		case <-ctx.Done():
+			// caller context is done
+			wg.Wait()
+			return make(map[string]interface{}), ctx.Err()
+		case res, ok := <-out:
+			if !ok {
+				return result, nil
+			}
+			result[res.field] = res.data
+		}
+	}
+}
+
+// workers - get count of aggregation goroutines
+func (search *Search) workers(fieldsCount int) int {
+	if search.options.Sequential {
+		return 1
+	}
+	workers := search.options.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if search.options.Pool != nil && workers > search.options.Pool.Size() {
+		workers = search.options.Pool.Size()
+	}
+	if workers > fieldsCount {
+		workers = fieldsCount
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	return workers
+}
+
+// aggregateSequential - aggregate fields in the calling goroutine
+func (search *Search) aggregateSequential(ctx context.Context, fields []string, aggregate func(fieldName string) (interface{}, error)) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(fields))
+	for _, name := range fields {
+		if err := ctx.Err(); err != nil {
+			return make(map[string]interface{}), err
+		}
+		data, err := aggregateField(ctx, search.options.Pool, name, aggregate)
+		if err != nil {
+			return make(map[string]interface{}), err
+		}
+		result[name] = data
+	}
+	return result, nil
+}
+
+// aggregateField - aggregate field using slot of the pool
+func aggregateField(ctx context.Context, pool *Pool, fieldName string, aggregate func(fieldName string) (interface{}, error)) (interface{}, error) {
+	if err := pool.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer pool.release()
+	return aggregate(fieldName)
+}
+
+// aggregateWorker - aggregation goroutine
+func aggregateWorker(
+	ctx context.Context, // cancel context
+	in chan string, // input channel
+	out chan *fieldResult, // results channel
+	errChan chan error, // channel for error messages
+	wg *sync.WaitGroup,
+	pool *Pool, // shared pool (optional)
+	aggregate func(fieldName string) (interface{}, error), // field aggregation
+) {
+	defer wg.Done()
+
+	for {
+		select {
+		// cancel command
+		case <-ctx.Done():
+			return
+
+		case fieldName, ok := <-in:
+			if !ok {
+				return
+			}
+
+			data, err := aggregateField(ctx, pool, fieldName, aggregate)
+			if err != nil {
+				// send error (will stop other goroutines)
+				select {
+				case errChan <- err:
+				case <-ctx.Done():
+				}
+				return
+			}
+
+			select {
+			case out <- &fieldResult{field: fieldName, data: data}:
+			case <-ctx.Done():
+				return
+			}
+			runtime.Gosched()
+		}
+	}
+}
+
+// fieldNames - get names of fields to aggregate
+func (agg *aggregation) fieldNames() []string {
+	if len(agg.query.Fields) > 0 {
+		result := make([]string, 0, len(agg.query.Fields))
+		for _, name := range agg.query.Fields {
+			if agg.search.index.HasField(name) {
+				result = append(result, name)
+			}
+		}
+		return result
+	}
+
+	fields := agg.search.index.GetFields()
+	result := make([]string, 0, len(fields))
+	for name := range fields {
+		result = append(result, name)
+	}
+	return result
+}
+
+// fieldRecords - find records for field aggregation, nil result means all records
+func (agg *aggregation) fieldRecords(fieldName string) (*recordSet, error) {
+	if len(agg.filters) == 0 && len(agg.records) == 0 {
+		return nil, nil
+	}
+	if agg.selfFiltering(fieldName) {
+		return agg.filtered, nil
+	}
+	// do not apply self filtering
+	if fieldFilters, ok := excludeFieldFilters(agg.filters, fieldName); ok {
+		if len(fieldFilters) == 0 && len(agg.records) == 0 {
+			return nil, nil
+		}
+		return agg.search.findRecords(agg.ctx, fieldFilters, agg.records)
+	}
+	return agg.filtered, nil
+}
+
+// selfFiltering - check if field filters are applied to the field aggregation
+func (agg *aggregation) selfFiltering(fieldName string) bool {
+	if agg.query.SelfFiltering {
+		return true
+	}
+	for _, name := range agg.query.SelfFilteringFields {
+		if name == fieldName {
+			return true
+		}
+	}
+	return false
+}
+
+// countField - count records for field values (or ranges)
+func (agg *aggregation) countField(fieldName string) (interface{}, error) {
+	records, err := agg.fieldRecords(fieldName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]int)
+	for _, v := range agg.countValues(fieldName, records) {
+		result[v.Value] = v.Count
+	}
+	if _, ok := result[agg.query.Missing]; ok && agg.query.Missing != "" {
+		return nil, fmt.Errorf("%w: field %s, value %q", ErrMissingConflict, fieldName, agg.query.Missing)
+	}
+
+	if limit, ok := agg.query.Limits[fieldName]; ok && limit > 0 {
+		result = topValues(result, limit)
+	}
+	if agg.query.Missing != "" {
+		if missing := agg.missingCount(fieldName, records); missing > 0 {
+			result[agg.query.Missing] = missing
+		}
+	}
+	return result, nil
+}
+
+// missingCount - count records without field value
+func (agg *aggregation) missingCount(fieldName string, records *recordSet) int {
+	fieldRecords := agg.search.index.GetField(fieldName).GetRecords()
+	if records == nil {
+		agg.totalOnce.Do(func() {
+			if agg.search.index.IsBitmap() {
+				agg.totalCount = agg.search.index.GetIdBitmap().Cardinality()
+			} else {
+				agg.totalCount = len(agg.search.index.GetIdList())
+			}
+		})
+		return agg.totalCount - fieldRecords.Count()
+	}
+	return records.count() - records.intersectCount(fieldRecords)
+}
+
+// countedValue - aggregation value (or range) with field values counted for it
+type countedValue struct {
+	ValueCount
+	values []*index.Value
+}
+
+// countValues - count records for field values (or ranges), values without records are skipped.
+// Ranges are listed in order of buckets
+func (agg *aggregation) countValues(fieldName string, records *recordSet) []countedValue {
+	field := agg.search.index.GetField(fieldName)
+	if rng, ok := agg.query.Ranges[fieldName]; ok {
+		return countRanges(field, rng, records)
+	}
+
+	result := make([]countedValue, 0, len(field.Values))
+	for vName, vList := range field.Values {
+		// get records count for filter field value
+		if intersect := records.intersectCount(vList); intersect > 0 {
+			result = append(result, countedValue{
+				ValueCount: ValueCount{Value: agg.valueName(field, vName), Count: intersect},
+				values:     []*index.Value{vList},
+			})
+		}
+	}
+	return result
+}
+
+// otherCount - count records which have values dropped by limit and have no listed values
+func otherCount(counted []countedValue, listed []ValueCount, records *recordSet) int {
+	keep := make(map[string]struct{}, len(listed))
+	for _, v := range listed {
+		keep[v.Value] = struct{}{}
+	}
+	other := make([]*index.Value, 0)
+	kept := make([]*index.Value, 0)
+	for _, v := range counted {
+		if _, ok := keep[v.Value]; ok {
+			kept = append(kept, v.values...)
+		} else {
+			other = append(other, v.values...)
+		}
+	}
+	return records.diffCount(other, kept)
+}
+
+// sortedField - get ordered list of field values with records count
+func (agg *aggregation) sortedField(fieldName string) (interface{}, error) {
+	records, err := agg.fieldRecords(fieldName)
+	if err != nil {
+		return nil, err
+	}
+
+	counted := agg.countValues(fieldName, records)
+	result := &FieldAggregation{Values: make([]ValueCount, 0, len(counted))}
+	for _, v := range counted {
+		result.Values = append(result.Values, v.ValueCount)
+	}
+
+	if limit, ok := agg.query.Limits[fieldName]; ok && limit > 0 && len(result.Values) > limit {
+		result.Values = keepTopValues(result.Values, limit)
+		result.Other = otherCount(counted, result.Values, records)
+	}
+	if agg.query.Missing != "" {
+		result.Missing = agg.missingCount(fieldName, records)
+	}
+
+	if _, ok := agg.query.Ranges[fieldName]; !ok {
+		order := agg.query.Order
+		if fieldOrder, ok := agg.query.FieldsOrder[fieldName]; ok {
+			order = fieldOrder
+		}
+		// values of numeric schema types are ordered as numbers
+		if order == ORDER_VALUE && index.IsNumeric(agg.search.index.GetFieldType(fieldName)) {
+			order = ORDER_NUMERIC
+		}
+		sortValues(result.Values, order)
+	}
+	return result, nil
+}
+
+// topValues - keep values with the largest records count, values with equal count are ordered by name
+func topValues(counts map[string]int, limit int) map[string]int {
+	if len(counts) <= limit {
+		return counts
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	result := make(map[string]int, limit)
+	for _, name := range names[:limit] {
+		result[name] = counts[name]
+	}
+	return result
+}
+
+// fieldStats - get statistics of numeric field values
+func (agg *aggregation) fieldStats(fieldName string) (interface{}, error) {
+	records, err := agg.fieldRecords(fieldName)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats *Stats
+	field := agg.search.index.GetField(fieldName)
+	// numeric values are sorted, the first found is min and the last one is max
+	for _, number := range field.GetNumericValues() {
+		count := records.intersectCount(field.Values[number.Name])
+		if count == 0 {
+			continue
+		}
+		if stats == nil {
+			stats = &Stats{Min: number.Number}
+		}
+		stats.Max = number.Number
+		stats.Count += count
+		stats.Sum += number.Number * float64(count)
+	}
+	if stats != nil {
+		stats.Avg = stats.Sum / float64(stats.Count)
+	}
+	return stats, nil
+}
+
+// availableValues - get field values which have found records
+func (agg *aggregation) availableValues(fieldName string) (interface{}, error) {
+	records, err := agg.fieldRecords(fieldName)
+	if err != nil {
+		return nil, err
+	}
+
+	field := agg.search.index.GetField(fieldName)
+	result := make([]string, 0, len(field.Values))
+	for vName, vList := range field.Values {
+		// stops on the first common record
+		if records.intersects(vList) {
+			result = append(result, agg.valueName(field, vName))
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// valueName - get value name for aggregation results
+func (agg *aggregation) valueName(field *index.Field, name string) string {
+	if agg.query.DisplayValues {
+		return field.GetDisplayValue(name)
+	}
+	return name
+}
+
+// countRanges - count records for numeric ranges of field values
+func countRanges(field *index.Field, rng *RangeAggregation, records *recordSet) []countedValue {
+	result := make([]countedValue, 0)
+	numbers := field.GetNumericValues()
+
+	// explicit ranges
+	if len(rng.Buckets) > 0 {
+		for i := range rng.Buckets {
+			bucket := &rng.Buckets[i]
+			start := 0
+			end := len(numbers)
+			if bucket.Type == filter.RANGE_BOTH || bucket.Type == filter.RANGE_MIN {
+				start = sort.Search(len(numbers), func(i int) bool { return numbers[i].Number >= bucket.From })
+			}
+			if bucket.Type == filter.RANGE_BOTH || bucket.Type == filter.RANGE_MAX {
+				end = sort.Search(len(numbers), func(i int) bool { return numbers[i].Number >= bucket.To })
+			}
+			if start >= end {
+				continue
+			}
+			values := fieldValues(field, numbers[start:end])
+			if count := records.unionCount(values); count > 0 {
+				result = append(result, countedValue{ValueCount: ValueCount{Value: bucket.GetName(), Count: count}, values: values})
+			}
+		}
+		return result
+	}
+
+	if rng.Interval <= 0 {
+		return result
+	}
+
+	// histogram, numbers are sorted so bucket values are neighbours
+	for start := 0; start < len(numbers); {
+		bucket := histogramBucket(numbers[start].Number, rng.Interval)
+		from := histogramBound(bucket, rng.Interval)
+		to := histogramBound(bucket+1, rng.Interval)
+		end := start + 1
+		for end < len(numbers) && numbers[end].Number < to {
+			end++
+		}
+		values := fieldValues(field, numbers[start:end])
+		if count := records.unionCount(values); count > 0 {
+			result = append(result, countedValue{ValueCount: ValueCount{Value: formatNumber(from) + "-" + formatNumber(to), Count: count}, values: values})
+		}
+		start = end
+	}
+	return result
+}
+
+// histogramBucket - get index of histogram bucket for number, bounds of buckets are rounded (see histogramBound)
+func histogramBucket(number float64, interval float64) float64 {
+	bucket := math.Floor(number / interval)
+	// fix float division error: 0.3 / 0.1 = 2.9999999999999996
+	if histogramBound(bucket+1, interval) <= number {
+		bucket++
+	} else if histogramBound(bucket, interval) > number {
+		bucket--
+	}
+	return bucket
+}
+
+// histogramBound - get lower bound of histogram bucket rounded to 12 significant digits (3 * 0.1 => 0.3)
+func histogramBound(bucket float64, interval float64) float64 {
+	bound, _ := strconv.ParseFloat(strconv.FormatFloat(bucket*interval, 'g', 12, 64), 64)
+	return bound
+}
+
+// fieldValues - get field values by numeric values list
+func fieldValues(field *index.Field, numbers []index.NumericValue) []*index.Value {
+	result := make([]*index.Value, 0, len(numbers))
+	for _, number := range numbers {
+		result = append(result, field.Values[number.Name])
+	}
+	return result
+}
+
+func formatNumber(number float64) string {
+	return strconv.FormatFloat(number, 'f', -1, 64)
+}
@@ -0,0 +1,142 @@
+package search
+
+import (
+	"sort"
+	"strconv"
+)
+
+// ORDER_COUNT - order values by records count (desc), values with equal count are ordered by name
+const ORDER_COUNT = 0
+
+// ORDER_VALUE - order values by name (string comparison), values of numeric schema types are ordered as numbers
+const ORDER_VALUE = 1
+
+// ORDER_NUMERIC - order values by numeric value, non-numeric values are listed after numeric ones
+const ORDER_NUMERIC = 2
+
+// ORDER_NATURAL - order values by name, digits are compared as numbers ("size2" < "size10")
+const ORDER_NATURAL = 3
+
+// ValueCount - field value with records count
+type ValueCount struct {
+	Value string
+	Count int
+}
+
+// FieldAggregation - ordered aggregation result of the field
+type FieldAggregation struct {
+	Values []ValueCount
+	// Other - count of records which have only values dropped by limit (each record is counted once)
+	Other int
+	// Missing - count of records without field value (if AggregationQuery.Missing is set)
+	Missing int
+}
+
+// sortValues - sort aggregation values
+func sortValues(values []ValueCount, order int) {
+	switch order {
+	case ORDER_VALUE:
+		sort.Slice(values, func(i, j int) bool { return values[i].Value < values[j].Value })
+	case ORDER_NUMERIC:
+		sortNumeric(values)
+	case ORDER_NATURAL:
+		sort.Slice(values, func(i, j int) bool { return naturalLess(values[i].Value, values[j].Value) })
+	default:
+		sort.Slice(values, func(i, j int) bool { return countLess(values[i], values[j]) })
+	}
+}
+
+// countLess - compare values by records count (desc) and name
+func countLess(a, b ValueCount) bool {
+	if a.Count != b.Count {
+		return a.Count > b.Count
+	}
+	return a.Value < b.Value
+}
+
+// keepTopValues - keep the first limit values with the largest records count, order of values is not changed
+func keepTopValues(values []ValueCount, limit int) []ValueCount {
+	sorted := make([]ValueCount, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool { return countLess(sorted[i], sorted[j]) })
+
+	keep := make(map[string]struct{}, limit)
+	for _, v := range sorted[:limit] {
+		keep[v.Value] = struct{}{}
+	}
+
+	top := make([]ValueCount, 0, limit)
+	for _, v := range values {
+		if _, ok := keep[v.Value]; ok {
+			top = append(top, v)
+		}
+	}
+	return top
+}
+
+func sortNumeric(values []ValueCount) {
+	numbers := make(map[string]float64, len(values))
+	for _, v := range values {
+		if n, err := strconv.ParseFloat(v.Value, 64); err == nil {
+			numbers[v.Value] = n
+		}
+	}
+	sort.Slice(values, func(i, j int) bool {
+		a, aOk := numbers[values[i].Value]
+		b, bOk := numbers[values[j].Value]
+		switch {
+		case aOk && bOk && a != b:
+			return a < b
+		case aOk != bOk:
+			return aOk
+		}
+		return values[i].Value < values[j].Value
+	})
+}
+
+// naturalLess - compare strings, sequences of digits are compared as numbers
+func naturalLess(a, b string) bool {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isDigit(a[i]) && isDigit(b[j]) {
+			// skip leading zeros
+			si, sj := i, j
+			for si < len(a) && a[si] == '0' {
+				si++
+			}
+			for sj < len(b) && b[sj] == '0' {
+				sj++
+			}
+			ei, ej := si, sj
+			for ei < len(a) && isDigit(a[ei]) {
+				ei++
+			}
+			for ej < len(b) && isDigit(b[ej]) {
+				ej++
+			}
+			// longer number is greater
+			if ei-si != ej-sj {
+				return ei-si < ej-sj
+			}
+			if a[si:ei] != b[sj:ej] {
+				return a[si:ei] < b[sj:ej]
+			}
+			// equal numbers, fewer leading zeros goes first
+			if ei-i != ej-j {
+				return ei-i < ej-j
+			}
+			i, j = ei, ej
+			continue
+		}
+		if a[i] != b[j] {
+			return a[i] < b[j]
+		}
+		i++
+		j++
+	}
+	return len(a)-i < len(b)-j
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,42 @@
+package search
+
+import "context"
+
+// Pool - limits count of field aggregations running at the same time
+type Pool struct {
+	slots chan struct{}
+}
+
+// NewPool - create pool for size concurrent field aggregations
+func NewPool(size int) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	return &Pool{slots: make(chan struct{}, size)}
+}
+
+// Size - get max count of concurrent aggregations
+func (pool *Pool) Size() int {
+	return cap(pool.slots)
+}
+
+// acquire - wait for free slot, returns ctx.Err() if context is done
+func (pool *Pool) acquire(ctx context.Context) error {
+	if pool == nil {
+		return nil
+	}
+	select {
+	case pool.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release - free slot
+func (pool *Pool) release() {
+	if pool == nil {
+		return
+	}
+	<-pool.slots
+}
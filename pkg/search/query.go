@@ -0,0 +1,93 @@
+package search
+
+import (
+	"context"
+	"github.com/k-samuel/go-faceted-search/pkg/filter"
+	"github.com/k-samuel/go-faceted-search/pkg/sorter"
+	"github.com/k-samuel/go-faceted-search/pkg/utils"
+)
+
+// Query - search query with sorting and pagination
+type Query struct {
+	// Filters - list of filters
+	Filters []filter.FilterInterface
+	// Records - limit search using list of record id (optional)
+	Records []int64
+	// SortField - sort results by field value (optional)
+	SortField string
+	// SortDirection - sorter.SORT_ASC or sorter.SORT_DESC
+	SortDirection int
+	// Sorter - sorter for SortField, sorter.SchemaSorter is used by default (string order for fields without schema type)
+	Sorter sorter.SorterInterface
+	// Offset - count of records to skip
+	Offset int
+	// Limit - max count of records in result, 0 - no limit
+	Limit int
+}
+
+// QueryResult - results page of search query
+type QueryResult struct {
+	// Total - count of records found
+	Total int
+	// Ids - record id of requested page
+	Ids []int64
+}
+
+// Query - find records using filters, sort them and get the requested page
+func (search *Search) Query(query *Query) (result *QueryResult, err error) {
+	return search.QueryContext(context.Background(), query)
+}
+
+// QueryContext - find records using filters, sort them and get the requested page, search is stopped when context is done
+func (search *Search) QueryContext(ctx context.Context, query *Query) (result *QueryResult, err error) {
+	result = &QueryResult{Ids: []int64{}}
+
+	ids, err := search.FindContext(ctx, query.Filters, query.Records)
+	if err != nil {
+		return result, err
+	}
+	result.Total = len(ids)
+
+	if query.SortField != "" && len(ids) > 0 {
+		ids, err = search.sortResults(query, ids)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	result.Ids = page(ids, query.Offset, query.Limit)
+	return result, err
+}
+
+// sortResults - sort found records by query field, records without field value are placed at the end
+func (search *Search) sortResults(query *Query, ids []int64) ([]int64, error) {
+	srt := query.Sorter
+	if srt == nil {
+		srt = sorter.NewSchemaSorter(search.index)
+	}
+	sorted, err := srt.Sort(ids, query.SortField, query.SortDirection)
+	if err != nil {
+		return ids, err
+	}
+	if len(sorted) < len(ids) {
+		sorted = append(sorted, utils.DiffSortedInt(ids, utils.Deduplicate(append([]int64{}, sorted...)))...)
+	}
+	return sorted, err
+}
+
+// page - get slice of records for offset and limit
+func page(ids []int64, offset, limit int) []int64 {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(ids) {
+		return []int64{}
+	}
+	end := len(ids)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	result := make([]int64, end-offset)
+	copy(result, ids[offset:end])
+	return result
+}
@@ -0,0 +1,129 @@
+package search
+
+import (
+	"github.com/k-samuel/go-faceted-search/pkg/bitmap"
+	"github.com/k-samuel/go-faceted-search/pkg/index"
+	"github.com/k-samuel/go-faceted-search/pkg/utils"
+)
+
+// recordSet - records found by filters, sorted list of record id or bitmap (index.STORAGE_BITMAP).
+// Aggregation uses nil set for all index records
+type recordSet struct {
+	ids    []int64
+	bitmap *bitmap.Bitmap
+}
+
+// getIds - get sorted list of record id
+func (set *recordSet) getIds() []int64 {
+	if set.bitmap != nil {
+		return set.bitmap.ToArray()
+	}
+	if set.ids == nil {
+		return []int64{}
+	}
+	return set.ids
+}
+
+// count - get count of records in set
+func (set *recordSet) count() int {
+	if set.bitmap != nil {
+		return set.bitmap.Cardinality()
+	}
+	return len(set.ids)
+}
+
+// intersectCount - get count of value records in set
+func (set *recordSet) intersectCount(value *index.Value) int {
+	if set == nil {
+		return value.Count()
+	}
+	if set.bitmap != nil {
+		return value.GetBitmap().AndCardinality(set.bitmap)
+	}
+	return utils.IntersectCountSortedInt(value.GetIds(), set.ids)
+}
+
+// intersects - check if set has at least one record of value
+func (set *recordSet) intersects(value *index.Value) bool {
+	if set == nil {
+		return value.Count() > 0
+	}
+	if set.bitmap != nil {
+		return value.GetBitmap().Intersects(set.bitmap)
+	}
+	return utils.HasIntersectSortedInt(value.GetIds(), set.ids)
+}
+
+// diffCount - get count of set records which are present in one of include values and are not present in exclude values
+func (set *recordSet) diffCount(include []*index.Value, exclude []*index.Value) int {
+	if len(include) == 0 {
+		return 0
+	}
+	if include[0].IsBitmap() {
+		diff := bitmap.New()
+		for _, v := range include {
+			diff.Or(v.GetBitmap())
+		}
+		for _, v := range exclude {
+			diff.AndNot(v.GetBitmap())
+		}
+		if set == nil {
+			return diff.Cardinality()
+		}
+		return diff.AndCardinality(set.bitmap)
+	}
+
+	ids := unionIds(include)
+	if excludeIds := unionIds(exclude); len(excludeIds) > 0 {
+		ids = utils.DiffSortedInt(ids, excludeIds)
+	}
+	if set == nil {
+		return len(ids)
+	}
+	return utils.IntersectCountSortedInt(ids, set.ids)
+}
+
+// unionIds - get sorted union of value record lists
+func unionIds(values []*index.Value) []int64 {
+	ids := make([]int64, 0)
+	for _, v := range values {
+		ids = append(ids, v.GetIds()...)
+	}
+	if len(ids) > 1 {
+		ids = utils.Deduplicate(ids)
+	}
+	return ids
+}
+
+// unionCount - get count of set records which are present at least in one of values
+func (set *recordSet) unionCount(values []*index.Value) int {
+	if len(values) == 0 {
+		return 0
+	}
+	if values[0].IsBitmap() {
+		union := bitmap.New()
+		for _, v := range values {
+			union.Or(v.GetBitmap())
+		}
+		if set == nil {
+			return union.Cardinality()
+		}
+		return union.AndCardinality(set.bitmap)
+	}
+
+	if len(values) == 1 {
+		return set.intersectCount(values[0])
+	}
+	ids := make([]int64, 0)
+	for _, v := range values {
+		ids = append(ids, v.GetIds()...)
+	}
+	if len(ids) == 0 {
+		return 0
+	}
+	ids = utils.Deduplicate(ids)
+	if set == nil {
+		return len(ids)
+	}
+	return utils.IntersectCountSortedInt(ids, set.ids)
+}
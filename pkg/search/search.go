@@ -2,32 +2,44 @@ package search
 
 import (
 	"context"
+	"github.com/k-samuel/go-faceted-search/pkg/bitmap"
 	"github.com/k-samuel/go-faceted-search/pkg/filter"
 	"github.com/k-samuel/go-faceted-search/pkg/index"
 	"github.com/k-samuel/go-faceted-search/pkg/utils"
 	"math"
-	"runtime"
 	"sort"
-	"sync"
 )
 
+// Options - search settings
+type Options struct {
+	// Workers - count of aggregation goroutines per call, runtime.NumCPU() by default
+	Workers int
+	// Pool - pool limiting count of field aggregations running at the same time,
+	// can be shared by several searches (optional)
+	Pool *Pool
+	// Sequential - aggregate fields in the calling goroutine (for small indexes), same as Workers = 1
+	Sequential bool
+}
+
 // Search - faceted search
 type Search struct {
-	index *index.Index
+	index   *index.Index
+	options Options
 }
 
 // NewSearch Create new search instance
 func NewSearch(index *index.Index) *Search {
+	return NewSearchWithOptions(index, Options{})
+}
+
+// NewSearchWithOptions - create new search instance with options
+func NewSearchWithOptions(index *index.Index, options Options) *Search {
 	var search Search
 	search.index = index
+	search.options = options
 	return &search
 }
 
-type filterCountInfo struct {
-	field string
-	data  map[string]int
-}
-
 // GetIndex get index storage
 func (search *Search) GetIndex() *index.Index {
 	return search.index
@@ -35,6 +47,13 @@ func (search *Search) GetIndex() *index.Index {
 
 // Find records using filters, limit search using list of recordId (optional)
 func (search *Search) Find(filters []filter.FilterInterface, inputRecords []int64) (result []int64, err error) {
+	return search.FindContext(context.Background(), filters, inputRecords)
+}
+
+// FindContext - find records using filters, search is stopped with ctx.Err() when context is done
+func (search *Search) FindContext(ctx context.Context, filters []filter.FilterInterface, inputRecords []int64) (result []int64, err error) {
+	search.index.RLock()
+	defer search.index.RUnlock()
 
 	if len(inputRecords) > 0 {
 		sort.Slice(inputRecords, func(i, j int) bool { return inputRecords[i] < inputRecords[j] })
@@ -46,14 +65,25 @@ func (search *Search) Find(filters []filter.FilterInterface, inputRecords []int6
 		filters = search.sortFilters(filters)
 	}
 
-	mapResult, err := search.findRecords(filters, inputRecords)
+	records, err := search.findRecords(ctx, filters, inputRecords)
 	if err != nil {
 		return []int64{}, err
 	}
-	return mapResult, err
+	return records.getIds(), err
 }
 
-func (search *Search) findRecords(filters []filter.FilterInterface, inputRecords []int64) (result []int64, err error) {
+// findRecords - find records using filters, input records should be sorted
+func (search *Search) findRecords(ctx context.Context, filters []filter.FilterInterface, inputRecords []int64) (*recordSet, error) {
+	if search.index.IsBitmap() {
+		result, err := search.findBitmap(ctx, filters, inputRecords)
+		return &recordSet{bitmap: result}, err
+	}
+	result, err := search.findList(ctx, filters, inputRecords)
+	return &recordSet{ids: result}, err
+}
+
+// findList - find records using sorted lists of record id
+func (search *Search) findList(ctx context.Context, filters []filter.FilterInterface, inputRecords []int64) (result []int64, err error) {
 
 	iLen := len(inputRecords)
 
@@ -68,180 +98,60 @@ func (search *Search) findRecords(filters []filter.FilterInterface, inputRecords
 		return result, err
 	}
 
-	// start value is inputRecords list
+	// start value is inputRecords list, empty list means all records
 	result = inputRecords
 
 	for _, fl := range filters {
-		fieldName := fl.GetFieldName()
-		if !search.index.HasField(fieldName) {
-			continue
+		// stop search if request is canceled
+		if err = ctx.Err(); err != nil {
+			return []int64{}, err
 		}
-		field := search.index.GetField(fieldName)
-		if !field.HasValues() {
+		result, err = filter.Apply(search.index, fl, result)
+		if err != nil {
+			return []int64{}, err
+		}
+		// empty result can not be limited by other filters
+		if len(result) == 0 {
 			return []int64{}, err
 		}
-		result, err = fl.FilterResults(field, result)
 	}
-
 	return result, err
 }
 
-// AggregateFilters - find acceptable filter values
-func (search *Search) AggregateFilters(filters []filter.FilterInterface, inputRecords []int64) (result map[string]map[string]int, err error) {
+// findBitmap - find records using bitmaps of record id
+func (search *Search) findBitmap(ctx context.Context, filters []filter.FilterInterface, inputRecords []int64) (result *bitmap.Bitmap, err error) {
 
+	// start value is inputRecords bitmap, nil means all records
 	if len(inputRecords) > 0 {
-		sort.Slice(inputRecords, func(i, j int) bool { return inputRecords[i] < inputRecords[j] })
+		result = bitmap.FromIds(inputRecords)
 	}
 
-	// Aggregates optimisation for value filters.
-	// The fewer elements after the first filtering, the fewer data copies and memory allocations in iterations
-	if len(inputRecords) == 0 && len(filters) > 1 {
-		filters = search.sortFilters(filters)
+	// return all records for empty filters
+	if len(filters) == 0 {
+		total := search.index.GetIdBitmap()
+		if result != nil {
+			total.And(result)
+		}
+		return total, err
 	}
 
-	indexedFilters := make(map[string]filter.FilterInterface, len(filters))
-	indexedFilteredRecords := make([]int64, 0, 100)
-	searchFields := search.index.GetFields()
-	result = make(map[string]map[string]int, len(searchFields))
-
-	if len(filters) > 0 {
-		// index filters by field
-		for _, filter := range filters {
-			indexedFilters[filter.GetFieldName()] = filter
+	for _, fl := range filters {
+		// stop search if request is canceled
+		if err = ctx.Err(); err != nil {
+			return bitmap.New(), err
 		}
-		indexedFilteredRecords, err = search.findRecords(filters, inputRecords)
+		result, err = filter.ApplyBitmap(search.index, fl, result)
 		if err != nil {
-			return result, err
-		}
-	} else {
-		if len(inputRecords) > 0 {
-			indexedFilteredRecords, err = search.findRecords(filters, inputRecords)
-			if err != nil {
-				return result, err
-			}
+			return bitmap.New(), err
 		}
-	}
-
-	// Create a cancel context for stopping on error
-	ctx := context.Background()
-	ctx, cancel := context.WithCancel(ctx)
-
-	wg := &sync.WaitGroup{}
-	in := make(chan string, len(searchFields))
-	out := make(chan *filterCountInfo, 10)
-	errChan := make(chan error)
-
-	go func() {
-		// aggregate fields in goroutines
-		for i := 0; i < runtime.NumCPU(); i++ {
-			wg.Add(1)
-			go search.aggregateField(ctx, in, out, errChan, wg, indexedFilters, indexedFilteredRecords, inputRecords)
-		}
-		wg.Wait()
-		close(out)
-	}()
-
-	// send fields into aggregation queue
-	for name := range searchFields {
-		in <- name
-	}
-	close(in)
-
-	// collect aggregation results
-Loop:
-	for {
-		select {
-		case err = <-errChan:
-			// send cancel to goroutines on field aggregation error
-			// no need to process full result
-			cancel()
-			// wait for goroutines stopped
-			wg.Wait()
-			result = make(map[string]map[string]int)
-			break Loop
-		case res, ok := <-out:
-			if !ok {
-				cancel()
-				break Loop
-			}
-			result[res.field] = res.data
+		// empty result can not be limited by other filters
+		if result.IsEmpty() {
+			return result, err
 		}
 	}
 	return result, err
 }
 
-// aggregateField - aggregation goroutine
-func (search *Search) aggregateField(
-	ctx context.Context, // cancel context
-	in chan string, // input channel
-	out chan *filterCountInfo, // results channel
-	errChan chan error, // channel for error messages
-	wg *sync.WaitGroup,
-	indexedFilters map[string]filter.FilterInterface, // filters indexed by field name
-	indexedFilteredRecords []int64, // Total list of record id suitable for filters conditions
-	inputRecords []int64, // input record id to search in
-) {
-	defer wg.Done()
-	var filtersCopy map[string]filter.FilterInterface
-	var recordIds []int64
-	var field *index.Field
-	var err error
-
-	fields := search.index.GetFields()
-
-	for {
-		select {
-		// cancel command
-		case <-ctx.Done():
-			return
-
-		case fieldName, ok := <-in:
-			if !ok {
-				return
-			}
-
-			result := &filterCountInfo{field: fieldName, data: make(map[string]int)}
-
-			field = fields[fieldName]
-			if len(indexedFilters) == 0 && len(inputRecords) == 0 {
-				// count values
-				for val, valueObj := range field.Values {
-					result.data[val] = len(valueObj.Ids)
-				}
-				out <- result
-				runtime.Gosched()
-				continue
-			}
-
-			// copy hash map
-			filtersCopy = copyFilterMap(indexedFilters)
-
-			// do not apply self filtering
-			if _, ok := filtersCopy[fieldName]; ok {
-				delete(filtersCopy, fieldName)
-				recordIds, err = search.findRecords(extractFilters(filtersCopy), inputRecords)
-				if err != nil {
-					// send error (will stop other goroutines)
-					errChan <- err
-					return
-				}
-			} else {
-				recordIds = indexedFilteredRecords
-			}
-
-			for vName, vList := range field.Values {
-				// get records count for filter field value
-				intersect := utils.IntersectCountSortedInt(vList.Ids, recordIds)
-				if intersect > 0 {
-					result.data[vName] = intersect
-				}
-			}
-			out <- result
-			runtime.Gosched()
-		}
-	}
-}
-
 type filterCount struct {
 	count  int
 	filter filter.FilterInterface
@@ -262,9 +172,18 @@ func (search *Search) sortFilters(filters []filter.FilterInterface) []filter.Fil
 	for index, item := range filters {
 
 		filterCnt := &filterCount{count: math.MaxInt, filter: filters[index]}
-		valFilter, ok := item.(*filter.ValueFilter)
 		counts = append(counts, filterCnt)
-		if !ok {
+
+		// Both value filters are estimated by the least used value.
+		// For intersection filter it is the upper limit of results count,
+		// also intersection starts from the smallest list of records
+		var values []string
+		switch valFilter := item.(type) {
+		case *filter.ValueFilter:
+			values = valFilter.Values
+		case *filter.ValueIntersectionFilter:
+			values = valFilter.Values
+		default:
 			continue
 		}
 
@@ -274,11 +193,11 @@ func (search *Search) sortFilters(filters []filter.FilterInterface) []filter.Fil
 			filterCnt.count = 0
 			continue
 		}
-		valuesInFilter = len(valFilter.Values)
+		valuesInFilter = len(values)
 		if valuesInFilter > 1 {
 			valuesCount = make([]*filterValuesCount, 0, valuesInFilter)
 		}
-		for _, val := range valFilter.Values {
+		for _, val := range values {
 			cnt := search.index.GetRecordsCount(fieldName, val)
 			if filterCnt.count > cnt {
 				filterCnt.count = cnt
@@ -291,10 +210,11 @@ func (search *Search) sortFilters(filters []filter.FilterInterface) []filter.Fil
 			sort.SliceStable(valuesCount, func(i, j int) bool {
 				return valuesCount[i].count < valuesCount[j].count
 			})
-			valFilter.Values = make([]string, 0, len(valuesCount))
+			sorted := make([]string, 0, len(valuesCount))
 			for _, v := range valuesCount {
-				valFilter.Values = append(valFilter.Values, v.value)
+				sorted = append(sorted, v.value)
 			}
+			filterCnt.filter = withValues(item, sorted)
 		}
 	}
 
@@ -309,18 +229,40 @@ func (search *Search) sortFilters(filters []filter.FilterInterface) []filter.Fil
 	return result
 }
 
-func extractFilters(filters map[string]filter.FilterInterface) []filter.FilterInterface {
-	var result = make([]filter.FilterInterface, 0, len(filters))
-	for _, filter := range filters {
-		result = append(result, filter)
+// withValues - copy value filter with new list of values, filters passed by caller are not changed
+// as they can be used by concurrent searches
+func withValues(item filter.FilterInterface, values []string) filter.FilterInterface {
+	switch valFilter := item.(type) {
+	case *filter.ValueFilter:
+		result := *valFilter
+		result.Values = values
+		return &result
+	case *filter.ValueIntersectionFilter:
+		result := *valFilter
+		result.Values = values
+		return &result
 	}
-	return result
+	return item
 }
 
-func copyFilterMap(input map[string]filter.FilterInterface) map[string]filter.FilterInterface {
-	result := make(map[string]filter.FilterInterface)
-	for k, v := range input {
-		result[k] = v
+// excludeFieldFilters - get list of filters without conditions for the field, returns false if there is nothing to exclude.
+// Composite filters are changed using filter.WithoutField
+func excludeFieldFilters(filters []filter.FilterInterface, fieldName string) ([]filter.FilterInterface, bool) {
+	found := false
+	for _, fl := range filters {
+		if filter.HasField(fl, fieldName) {
+			found = true
+			break
+		}
 	}
-	return result
+	if !found {
+		return filters, false
+	}
+	result := make([]filter.FilterInterface, 0, len(filters))
+	for _, fl := range filters {
+		if fl = filter.WithoutField(fl, fieldName); fl != nil {
+			result = append(result, fl)
+		}
+	}
+	return result, true
 }
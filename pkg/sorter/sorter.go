@@ -8,5 +8,5 @@ const SORT_DESC int = 1
 
 // SorterInterface - interface for facet data sorters realisation
 type SorterInterface interface {
-	Sort(results []int64, field string, direction int) []int64
+	Sort(results []int64, field string, direction int) ([]int64, error)
 }
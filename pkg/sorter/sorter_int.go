@@ -22,6 +22,8 @@ func NewIntSorter(index *index.Index) *IntSorter {
 
 // Sort - sort faceted search results by field using index data
 func (sorter *IntSorter) Sort(results []int64, field string, direction int) (result []int64, err error) {
+	sorter.index.RLock()
+	defer sorter.index.RUnlock()
 
 	if !sorter.index.HasField(field) {
 		err = errors.New("sort by undefined field: " + field)
@@ -65,7 +67,7 @@ func (sorter *IntSorter) Sort(results []int64, field string, direction int) (res
 			return result, err
 		}
 		if _, ok := fieldData.Values[str]; ok {
-			ids := utils.IntersectRecAndMapKeys(fieldData.Values[str].Ids, resultsMap)
+			ids := utils.IntersectRecAndMapKeys(fieldData.Values[str].GetIds(), resultsMap)
 			if len(ids) == 0 {
 				continue
 			}
@@ -0,0 +1,76 @@
+package sorter
+
+import (
+	"errors"
+	"github.com/k-samuel/go-faceted-search/pkg/index"
+	"github.com/k-samuel/go-faceted-search/pkg/utils"
+	"sort"
+)
+
+// SchemaSorter - sorter which picks order by field type of index schema:
+// integer, float, date and bool fields are sorted as numbers, other fields as strings
+type SchemaSorter struct {
+	index *index.Index
+}
+
+// NewSchemaSorter - sorter constructor
+func NewSchemaSorter(index *index.Index) *SchemaSorter {
+	var sorter SchemaSorter
+	sorter.index = index
+	return &sorter
+}
+
+// Sort - sort faceted search results by field using index data, records without field value are skipped
+func (sorter *SchemaSorter) Sort(results []int64, field string, direction int) (result []int64, err error) {
+	sorter.index.RLock()
+	defer sorter.index.RUnlock()
+
+	if !sorter.index.HasField(field) {
+		err = errors.New("sort by undefined field: " + field)
+		return nil, err
+	}
+
+	fieldData := sorter.index.GetField(field)
+	fieldType := fieldData.GetType()
+
+	var s []string
+	if index.IsNumeric(fieldType) || fieldType == index.FIELD_BOOL {
+		numbers := fieldData.GetNumericValues()
+		s = make([]string, 0, len(numbers))
+		for _, v := range numbers {
+			s = append(s, v.Name)
+		}
+		if direction != SORT_ASC {
+			for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+				s[i], s[j] = s[j], s[i]
+			}
+		}
+	} else {
+		s = make([]string, 0, len(fieldData.Values))
+		for name := range fieldData.Values {
+			s = append(s, name)
+		}
+		if direction == SORT_ASC {
+			sort.Sort(sort.StringSlice(s))
+		} else {
+			sort.Sort(sort.Reverse(sort.StringSlice(s)))
+		}
+	}
+
+	// flip results to map
+	resultsMap := make(map[int64]struct{}, len(results))
+	for _, v := range results {
+		resultsMap[v] = struct{}{}
+	}
+
+	result = make([]int64, 0, len(results))
+	for _, v := range s {
+		// record with several values is placed by the first one
+		ids := utils.IntersectRecAndMapKeys(fieldData.Values[v].GetIds(), resultsMap)
+		for _, k := range ids {
+			result = append(result, k)
+			delete(resultsMap, k)
+		}
+	}
+	return result, err
+}
@@ -21,6 +21,8 @@ func NewStringSorter(index *index.Index) *StringSorter {
 
 // Sort - sort faceted search results by field using index data
 func (sorter *StringSorter) Sort(results []int64, field string, direction int) (result []int64, err error) {
+	sorter.index.RLock()
+	defer sorter.index.RUnlock()
 
 	if !sorter.index.HasField(field) {
 		err = errors.New("sort by undefined field: " + field)
@@ -52,7 +54,7 @@ func (sorter *StringSorter) Sort(results []int64, field string, direction int) (
 
 	for _, v := range s {
 		if _, ok := fieldData.Values[v]; ok {
-			ids := utils.IntersectRecAndMapKeys(fieldData.Values[v].Ids, resultsMap)
+			ids := utils.IntersectRecAndMapKeys(fieldData.Values[v].GetIds(), resultsMap)
 			if len(ids) == 0 {
 				continue
 			}
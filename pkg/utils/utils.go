@@ -152,3 +152,106 @@ func Deduplicate(in []int64) []int64 {
 	result := in[:j+1]
 	return result
 }
+
+// DiffSortedInt get values of sorted slice a which are not present in sorted slice b
+func DiffSortedInt(a, b []int64) []int64 {
+	if len(a) == 0 {
+		return []int64{}
+	}
+	if len(b) == 0 {
+		result := make([]int64, len(a))
+		copy(result, a)
+		return result
+	}
+
+	compareCount := len(b)
+	comparePointer := 0
+
+	result := make([]int64, 0, len(a))
+
+	for _, value := range a {
+		for comparePointer < compareCount && b[comparePointer] < value {
+			comparePointer++
+		}
+		if comparePointer < compareCount && b[comparePointer] == value {
+			continue
+		}
+		result = append(result, value)
+	}
+	return result
+}
+
+// UnionSortedInt get sorted union of sorted int slices without duplicates, slices are merged by pairs
+func UnionSortedInt(lists [][]int64) []int64 {
+	if len(lists) == 0 {
+		return []int64{}
+	}
+	for len(lists) > 1 {
+		merged := make([][]int64, 0, (len(lists)+1)/2)
+		for i := 0; i < len(lists); i += 2 {
+			if i+1 == len(lists) {
+				merged = append(merged, lists[i])
+				continue
+			}
+			merged = append(merged, mergeSortedInt(lists[i], lists[i+1]))
+		}
+		lists = merged
+	}
+	result := make([]int64, len(lists[0]))
+	copy(result, lists[0])
+	return result
+}
+
+// mergeSortedInt get sorted union of two sorted int slices
+func mergeSortedInt(a, b []int64) []int64 {
+	result := make([]int64, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			result = append(result, a[i])
+			i++
+		case a[i] > b[j]:
+			result = append(result, b[j])
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	result = append(result, a[i:]...)
+	return append(result, b[j:]...)
+}
+
+// HasIntersectSortedInt check if sorted int slices have at least one common value.
+// Values of the shorter slice are searched in the longer one using galloping search
+func HasIntersectSortedInt(a, b []int64) bool {
+	if len(a) == 0 || len(b) == 0 || a[len(a)-1] < b[0] || b[len(b)-1] < a[0] {
+		return false
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	pos := 0
+	for _, value := range a {
+		// find range of b which can contain value
+		step := 1
+		for pos+step < len(b) && b[pos+step] < value {
+			pos += step
+			step <<= 1
+		}
+		end := pos + step + 1
+		if end > len(b) {
+			end = len(b)
+		}
+		pos += sort.Search(end-pos, func(i int) bool { return b[pos+i] >= value })
+		if pos == len(b) {
+			return false
+		}
+		if b[pos] == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,404 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/k-samuel/go-faceted-search/pkg/filter"
+	"github.com/k-samuel/go-faceted-search/pkg/search"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func getAggregateTestData() []map[string]interface{} {
+	return []map[string]interface{}{
+		{"price": 5, "color": "black", "size": []interface{}{7, 8}},
+		{"price": 15, "color": "black", "size": 8},
+		{"price": 20, "color": "white", "size": 7},
+		{"price": 99.5, "color": "yellow", "size": []interface{}{7, 9}},
+		{"price": 150, "color": "black", "size": 7},
+	}
+}
+
+func TestAggregateRanges(t *testing.T) {
+	for _, idx := range createStorageIndexes(getAggregateTestData()) {
+		facet := search.NewSearch(idx)
+
+		info, err := facet.Aggregate(&search.AggregationQuery{
+			Filters: []filter.FilterInterface{
+				&filter.ValueFilter{FieldName: "color", Values: []string{"black"}},
+				&filter.RangeFilter{FieldName: "price", Values: filter.Range{Min: 10, Max: 100, Type: filter.RANGE_BOTH}},
+			},
+			Ranges: map[string]*search.RangeAggregation{
+				"price": {Buckets: []search.Bucket{
+					{Type: filter.RANGE_MAX, To: 10},
+					{Type: filter.RANGE_BOTH, From: 10, To: 20},
+					{Type: filter.RANGE_BOTH, From: 20, To: 100, Name: "medium"},
+					{Type: filter.RANGE_MIN, From: 100},
+				}},
+				"size": {Interval: 2},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp := map[string]map[string]int{
+			"price": {"*-10": 1, "10-20": 1, "100+": 1},
+			"color": {"black": 1, "white": 1, "yellow": 1},
+			"size":  {"8-10": 1},
+		}
+		if !reflect.DeepEqual(exp, info) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, exp)
+		}
+
+		// histogram without filters, record is counted once per bucket
+		info, _ = facet.Aggregate(&search.AggregationQuery{
+			Ranges: map[string]*search.RangeAggregation{
+				"size":  {Interval: 2},
+				"price": {Interval: 50},
+			},
+		})
+		exp = map[string]map[string]int{
+			"price": {"0-50": 3, "50-100": 1, "150-200": 1},
+			"color": {"black": 3, "white": 1, "yellow": 1},
+			"size":  {"6-8": 4, "8-10": 3},
+		}
+		if !reflect.DeepEqual(exp, info) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, exp)
+		}
+	}
+}
+
+func TestAggregateRangeBuckets(t *testing.T) {
+	data := []map[string]interface{}{}
+	for _, v := range []float64{0.1, 0.3, 0.7, 10, 20, 20, 35} {
+		data = append(data, map[string]interface{}{"weight": v})
+	}
+	for _, idx := range createStorageIndexes(data) {
+		facet := search.NewSearch(idx)
+
+		// bounds of histogram buckets are not affected by float error
+		info, _ := facet.Aggregate(&search.AggregationQuery{
+			Fields: []string{"weight"},
+			Ranges: map[string]*search.RangeAggregation{"weight": {Interval: 0.1}},
+		})
+		exp := map[string]int{"0.1-0.2": 1, "0.3-0.4": 1, "0.7-0.8": 1, "10-10.1": 1, "20-20.1": 2, "35-35.1": 1}
+		if !reflect.DeepEqual(exp, info["weight"]) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info["weight"], exp)
+		}
+
+		// bucket filter finds records counted in the bucket
+		buckets := []search.Bucket{
+			{Type: filter.RANGE_MAX, To: 10},
+			{Type: filter.RANGE_BOTH, From: 10, To: 20},
+			{Type: filter.RANGE_BOTH, From: 20, To: 35},
+			{Type: filter.RANGE_MIN, From: 35},
+		}
+		info, _ = facet.Aggregate(&search.AggregationQuery{
+			Fields: []string{"weight"},
+			Ranges: map[string]*search.RangeAggregation{"weight": {Buckets: buckets}},
+		})
+		for _, bucket := range buckets {
+			res, _ := facet.Find([]filter.FilterInterface{bucket.Filter("weight")}, []int64{})
+			if len(res) != info["weight"][bucket.GetName()] {
+				t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", len(res), info["weight"][bucket.GetName()])
+			}
+		}
+	}
+}
+
+func TestAggregateStats(t *testing.T) {
+	facet := search.NewSearch(createIndex(getAggregateTestData()))
+
+	info, err := facet.AggregateStats(&search.AggregationQuery{
+		Filters: []filter.FilterInterface{
+			&filter.ValueFilter{FieldName: "color", Values: []string{"black"}},
+			&filter.RangeFilter{FieldName: "price", Values: filter.Range{Min: 10, Type: filter.RANGE_MIN}},
+		},
+	}, []string{"price", "size", "color", "undefined"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string]*search.Stats{
+		// price filter is not applied to price stats
+		"price": {Count: 3, Min: 5, Max: 150, Sum: 170, Avg: 170.0 / 3},
+		"size":  {Count: 2, Min: 7, Max: 8, Sum: 15, Avg: 7.5},
+	}
+	if !reflect.DeepEqual(exp, info) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, exp)
+	}
+
+	info, _ = facet.AggregateStats(&search.AggregationQuery{Records: []int64{3, 4}}, []string{"price"})
+	exp = map[string]*search.Stats{
+		"price": {Count: 2, Min: 20, Max: 99.5, Sum: 119.5, Avg: 59.75},
+	}
+	if !reflect.DeepEqual(exp, info) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, exp)
+	}
+}
+
+func TestAggregateFields(t *testing.T) {
+	facet := search.NewSearch(createIndex(getAggregateTestData()))
+
+	info, err := facet.Aggregate(&search.AggregationQuery{
+		Filters: []filter.FilterInterface{
+			&filter.ValueFilter{FieldName: "size", Values: []string{"7"}},
+		},
+		Fields: []string{"color", "size", "undefined"},
+		Limits: map[string]int{"color": 2, "size": 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string]map[string]int{
+		"color": {"black": 2, "white": 1},
+		"size":  {"7": 4},
+	}
+	if !reflect.DeepEqual(exp, info) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, exp)
+	}
+}
+
+func TestAggregateSelfFiltering(t *testing.T) {
+	facet := search.NewSearch(createIndex(getIndexTestData()))
+	filters := []filter.FilterInterface{
+		&filter.ValueFilter{FieldName: "color", Values: []string{"black"}},
+		&filter.ValueFilter{FieldName: "size", Values: []string{"7"}},
+	}
+
+	info, _ := facet.Aggregate(&search.AggregationQuery{Filters: filters, SelfFiltering: true})
+	exp := map[string]map[string]int{
+		"color": {"black": 2},
+		"size":  {"7": 2},
+		"group": {"A": 1, "C": 1},
+	}
+	if !reflect.DeepEqual(exp, info) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, exp)
+	}
+
+	info, _ = facet.Aggregate(&search.AggregationQuery{Filters: filters, SelfFilteringFields: []string{"color"}})
+	exp = map[string]map[string]int{
+		"color": {"black": 2},
+		"size":  {"7": 2, "8": 1},
+		"group": {"A": 1, "C": 1},
+	}
+	if !reflect.DeepEqual(exp, info) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, exp)
+	}
+}
+
+func TestAggregateValues(t *testing.T) {
+	for _, idx := range createStorageIndexes(getIndexTestData()) {
+		facet := search.NewSearch(idx)
+		info, err := facet.AggregateValues(&search.AggregationQuery{
+			Filters: []filter.FilterInterface{
+				&filter.ValueFilter{FieldName: "color", Values: []string{"black"}},
+				&filter.ValueFilter{FieldName: "size", Values: []string{"7"}},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp := map[string][]string{
+			"color": {"black", "white", "yellow"},
+			"size":  {"7", "8"},
+			"group": {"A", "C"},
+		}
+		if !reflect.DeepEqual(exp, info) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, exp)
+		}
+
+		info, _ = facet.AggregateValues(&search.AggregationQuery{Fields: []string{"group"}})
+		exp = map[string][]string{"group": {"A", "B", "C"}}
+		if !reflect.DeepEqual(exp, info) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, exp)
+		}
+	}
+}
+
+func TestAggregateSorted(t *testing.T) {
+	facet := search.NewSearch(createIndex([]map[string]interface{}{
+		{"name": "item10", "size": 10, "color": "black"},
+		{"name": "item2", "size": 2, "color": "black"},
+		{"name": "item1", "size": 9.5, "color": "white"},
+		{"name": "item02", "size": "XL", "color": "yellow"},
+		{"name": "item2", "size": 10, "color": "black"},
+		{"name": "item10", "size": 100, "color": "white"},
+	}))
+
+	info, err := facet.AggregateSorted(&search.AggregationQuery{
+		FieldsOrder: map[string]int{"name": search.ORDER_NATURAL, "size": search.ORDER_NUMERIC},
+		Limits:      map[string]int{"color": 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string]*search.FieldAggregation{
+		"name": {Values: []search.ValueCount{
+			{Value: "item1", Count: 1}, {Value: "item2", Count: 2}, {Value: "item02", Count: 1}, {Value: "item10", Count: 2},
+		}},
+		"size": {Values: []search.ValueCount{
+			{Value: "2", Count: 1}, {Value: "9.5", Count: 1}, {Value: "10", Count: 2}, {Value: "100", Count: 1}, {Value: "XL", Count: 1},
+		}},
+		"color": {Values: []search.ValueCount{{Value: "black", Count: 3}, {Value: "white", Count: 2}}, Other: 1},
+	}
+	if !reflect.DeepEqual(exp, info) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, exp)
+	}
+
+	info, _ = facet.AggregateSorted(&search.AggregationQuery{
+		Fields: []string{"color", "size"},
+		Order:  search.ORDER_VALUE,
+		Limits: map[string]int{"color": 2},
+		Ranges: map[string]*search.RangeAggregation{
+			"size": {Buckets: []search.Bucket{
+				{Type: filter.RANGE_MIN, From: 10},
+				{Type: filter.RANGE_MAX, To: 10},
+			}},
+		},
+	})
+	exp = map[string]*search.FieldAggregation{
+		"size":  {Values: []search.ValueCount{{Value: "10+", Count: 3}, {Value: "*-10", Count: 2}}},
+		"color": {Values: []search.ValueCount{{Value: "black", Count: 3}, {Value: "white", Count: 2}}, Other: 1},
+	}
+	if !reflect.DeepEqual(exp, info) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, exp)
+	}
+
+	// records with several dropped values are counted once, records with listed values are not counted
+	for _, idx := range createStorageIndexes([]map[string]interface{}{
+		{"tag": []interface{}{"a", "b"}},
+		{"tag": []interface{}{"a"}},
+		{"tag": []interface{}{"a", "c"}},
+		{"tag": []interface{}{"c", "d"}},
+		{"tag": []interface{}{"d", "e"}},
+	}) {
+		info, _ = search.NewSearch(idx).AggregateSorted(&search.AggregationQuery{Limits: map[string]int{"tag": 1}})
+		expTag := &search.FieldAggregation{Values: []search.ValueCount{{Value: "a", Count: 3}}, Other: 2}
+		if !reflect.DeepEqual(expTag, info["tag"]) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info["tag"], expTag)
+		}
+	}
+}
+
+func TestAggregateContext(t *testing.T) {
+	facet := search.NewSearch(createIndex(getIndexTestData()))
+	filters := []filter.FilterInterface{
+		&filter.ValueFilter{FieldName: "color", Values: []string{"black"}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	info, err := facet.AggregateFiltersContext(ctx, filters, []int64{})
+	if err != nil || len(info) != 3 {
+		t.Errorf("results not match\nGot:\n%v %v", info, err)
+	}
+
+	cancel()
+	info, err = facet.AggregateFiltersContext(ctx, filters, []int64{})
+	if err != context.Canceled || len(info) != 0 {
+		t.Errorf("results not match\nGot:\n%v %v\nExpected:\n%v", info, err, context.Canceled)
+	}
+
+	res, err := facet.FindContext(ctx, filters, []int64{})
+	if err != context.Canceled || len(res) != 0 {
+		t.Errorf("results not match\nGot:\n%v %v\nExpected:\n%v", res, err, context.Canceled)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	_, err = facet.AggregateContext(ctx, &search.AggregationQuery{})
+	if err != context.DeadlineExceeded {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestAggregateWorkers(t *testing.T) {
+	idx := createIndex(getIndexTestData())
+	filters := []filter.FilterInterface{
+		&filter.ValueFilter{FieldName: "color", Values: []string{"black"}},
+		&filter.ValueFilter{FieldName: "size", Values: []string{"7"}},
+	}
+	exp, _ := search.NewSearch(idx).AggregateFilters(filters, []int64{})
+
+	pool := search.NewPool(2)
+	options := []search.Options{
+		{Sequential: true},
+		{Workers: 1},
+		{Workers: 2},
+		{Workers: 100, Pool: pool},
+		{Pool: pool},
+	}
+	var wg sync.WaitGroup
+	for _, o := range options {
+		facet := search.NewSearchWithOptions(idx, o)
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				info, err := facet.AggregateFilters(filters, []int64{})
+				if err != nil || !reflect.DeepEqual(exp, info) {
+					t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, exp)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := search.NewSearchWithOptions(idx, search.Options{Sequential: true}).AggregateContext(ctx, &search.AggregationQuery{})
+	if err != context.Canceled {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", err, context.Canceled)
+	}
+}
+
+func TestAggregateMissing(t *testing.T) {
+	data := []map[string]interface{}{
+		{"color": "black", "size": 7},
+		{"color": "black"},
+		{"color": "white", "size": 8},
+		{"color": "white"},
+		{"color": "red", "size": 7},
+	}
+	for _, idx := range createStorageIndexes(data) {
+		facet := search.NewSearch(idx)
+
+		info, _ := facet.Aggregate(&search.AggregationQuery{Missing: "not specified"})
+		exp := map[string]map[string]int{
+			"color": {"black": 2, "white": 2, "red": 1},
+			"size":  {"7": 2, "8": 1, "not specified": 2},
+		}
+		if !reflect.DeepEqual(exp, info) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, exp)
+		}
+
+		query := &search.AggregationQuery{
+			Filters: []filter.FilterInterface{
+				&filter.ValueFilter{FieldName: "color", Values: []string{"white"}},
+				&filter.MissingFilter{FieldName: "size"},
+			},
+			Missing: "not specified",
+		}
+		info, _ = facet.Aggregate(query)
+		exp = map[string]map[string]int{
+			"color": {"black": 1, "white": 1},
+			"size":  {"8": 1, "not specified": 1},
+		}
+		if !reflect.DeepEqual(exp, info) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, exp)
+		}
+
+		sorted, _ := facet.AggregateSorted(query)
+		expSorted := &search.FieldAggregation{Values: []search.ValueCount{{Value: "8", Count: 1}}, Missing: 1}
+		if !reflect.DeepEqual(expSorted, sorted["size"]) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", sorted["size"], expSorted)
+		}
+
+		// count of records without value is not mixed with the same field value
+		if _, err := facet.Aggregate(&search.AggregationQuery{Missing: "white"}); !errors.Is(err, search.ErrMissingConflict) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+}
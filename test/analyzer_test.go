@@ -0,0 +1,122 @@
+package test
+
+import (
+	"bytes"
+	"github.com/k-samuel/go-faceted-search/pkg/filter"
+	"github.com/k-samuel/go-faceted-search/pkg/index"
+	"github.com/k-samuel/go-faceted-search/pkg/search"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzers(t *testing.T) {
+	idx := index.NewIndexWithOptions(index.Options{Analyzers: map[string]index.Analyzer{
+		"color": index.NewChainAnalyzer(
+			index.TrimAnalyzer,
+			index.LowercaseAnalyzer,
+			index.FoldAnalyzer,
+			index.NewSynonymAnalyzer(map[string]string{"noir": "black"}),
+		),
+	}})
+	data := []map[string]interface{}{
+		{"color": "Black", "size": 7},
+		{"color": "black ", "size": 8},
+		{"color": "BLACK", "size": 7},
+		{"color": "Noir", "size": 7},
+		{"color": "Café", "size": 8},
+		{"color": "Ｃａｆé", "size": 7},
+	}
+	for i, v := range data {
+		idx.Add(int64(i+1), v)
+	}
+	idx.CommitChanges()
+	facet := search.NewSearch(idx)
+
+	res, _ := facet.Find([]filter.FilterInterface{
+		&filter.ValueFilter{FieldName: "color", Values: []string{" BLACK"}},
+	}, []int64{})
+	exp := []int64{1, 2, 3, 4}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+	}
+
+	res, _ = facet.Find([]filter.FilterInterface{
+		&filter.ExcludeValueFilter{FieldName: "color", Values: []string{"NOIR"}},
+	}, []int64{})
+	exp = []int64{5, 6}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+	}
+
+	info, _ := facet.Aggregate(&search.AggregationQuery{})
+	expInfo := map[string]map[string]int{
+		"color": {"black": 4, "cafe": 2},
+		"size":  {"7": 4, "8": 2},
+	}
+	if !reflect.DeepEqual(expInfo, info) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, expInfo)
+	}
+
+	info, _ = facet.Aggregate(&search.AggregationQuery{DisplayValues: true})
+	expInfo = map[string]map[string]int{
+		"color": {"Black": 4, "Café": 2},
+		"size":  {"7": 4, "8": 2},
+	}
+	if !reflect.DeepEqual(expInfo, info) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, expInfo)
+	}
+
+	// display form of removed value is not kept
+	for _, id := range []int64{5, 6} {
+		idx.Delete(id)
+	}
+	idx.Add(7, map[string]interface{}{"color": "CAFE"})
+	if display := idx.GetField("color").GetDisplayValue("cafe"); display != "CAFE" {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", display, "CAFE")
+	}
+}
+
+func TestNormalizationAnalyzers(t *testing.T) {
+	cases := []struct {
+		analyzer index.Analyzer
+		value    string
+		exp      string
+	}{
+		{index.NFCAnalyzer, "café", "café"},
+		{index.NFCAnalyzer, "Ｃａｆé", "Ｃａｆé"},
+		{index.NFKCAnalyzer, "Ｃａｆé", "Café"},
+		{index.NFKCAnalyzer, "ﬁle", "file"},
+		{index.FoldAnalyzer, "résumé", "resume"},
+		{index.FoldAnalyzer, "café", "cafe"},
+		// combining marks of other scripts are meaningful
+		{index.FoldAnalyzer, "हिन्दी", "हिन्दी"},
+		{index.FoldAnalyzer, "שָׁלוֹם", "שָׁלוֹם"},
+	}
+	for _, c := range cases {
+		if res := c.analyzer.Analyze(c.value); res != c.exp {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, c.exp)
+		}
+	}
+}
+
+func TestAnalyzerDisplayWriteRead(t *testing.T) {
+	options := index.Options{Analyzers: map[string]index.Analyzer{"color": index.LowercaseAnalyzer}}
+	idx := index.NewIndexWithOptions(options)
+	idx.Add(1, map[string]interface{}{"color": "Black"})
+	idx.Add(2, map[string]interface{}{"color": "white"})
+
+	var buf bytes.Buffer
+	if _, err := idx.WriteTo(&buf); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	loaded, err := index.ReadFromWithOptions(&buf, options)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+
+	info, _ := search.NewSearch(loaded).Aggregate(&search.AggregationQuery{DisplayValues: true})
+	exp := map[string]map[string]int{"color": {"Black": 1, "white": 1}}
+	if !reflect.DeepEqual(exp, info) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, exp)
+	}
+}
@@ -0,0 +1,127 @@
+package test
+
+import (
+	"github.com/k-samuel/go-faceted-search/pkg/bitmap"
+	"github.com/k-samuel/go-faceted-search/pkg/filter"
+	"github.com/k-samuel/go-faceted-search/pkg/index"
+	"github.com/k-samuel/go-faceted-search/pkg/search"
+	"github.com/k-samuel/go-faceted-search/pkg/utils"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func randomIds(r *rand.Rand, count int, max int64) []int64 {
+	ids := make([]int64, 0, count)
+	for i := 0; i < count; i++ {
+		ids = append(ids, r.Int63n(max)-max/10)
+	}
+	return utils.Deduplicate(ids)
+}
+
+func TestBitmap(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	// sparse and dense containers
+	cases := [][2]int{{10, 100}, {3000, 70000}, {20000, 70000}, {100000, 300000}}
+
+	for _, c := range cases {
+		a := randomIds(r, c[0], int64(c[1]))
+		b := randomIds(r, c[0]/2, int64(c[1]))
+		bmA := bitmap.FromIds(a)
+		bmB := bitmap.FromIds(b)
+
+		if !reflect.DeepEqual(a, bmA.ToArray()) {
+			t.Fatalf("bitmap values not match")
+		}
+		if bmA.Cardinality() != len(a) {
+			t.Errorf("cardinality not match\nGot:\n%v\nExpected:\n%v", bmA.Cardinality(), len(a))
+		}
+
+		and := bmA.Clone()
+		and.And(bmB)
+		exp := utils.IntersectSortedInt(a, b)
+		if !reflect.DeepEqual(exp, and.ToArray()) {
+			t.Errorf("intersection not match for case %v", c)
+		}
+		if bmA.AndCardinality(bmB) != len(exp) {
+			t.Errorf("intersection count not match\nGot:\n%v\nExpected:\n%v", bmA.AndCardinality(bmB), len(exp))
+		}
+		if bmA.Intersects(bmB) != (len(exp) > 0) {
+			t.Errorf("intersects result not match for case %v", c)
+		}
+
+		or := bmA.Clone()
+		or.Or(bmB)
+		exp = utils.Deduplicate(append(append([]int64{}, a...), b...))
+		if !reflect.DeepEqual(exp, or.ToArray()) {
+			t.Errorf("union not match for case %v", c)
+		}
+
+		andNot := bmA.Clone()
+		andNot.AndNot(bmB)
+		exp = utils.DiffSortedInt(a, b)
+		if !reflect.DeepEqual(exp, andNot.ToArray()) {
+			t.Errorf("difference not match for case %v", c)
+		}
+
+		if !reflect.DeepEqual(a, bmA.ToArray()) || !reflect.DeepEqual(b, bmB.ToArray()) {
+			t.Errorf("source bitmaps are changed for case %v", c)
+		}
+
+		for _, v := range b {
+			bmA.Remove(v)
+		}
+		if !reflect.DeepEqual(andNot.ToArray(), bmA.ToArray()) {
+			t.Errorf("remove result not match for case %v", c)
+		}
+	}
+}
+
+func TestBitmapIndex(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	colors := []string{"red", "green", "blue", "black"}
+	data := make([]map[string]interface{}, 0, 5000)
+	for i := 0; i < 5000; i++ {
+		data = append(data, map[string]interface{}{
+			"color":     colors[r.Intn(len(colors))],
+			"size":      r.Intn(10),
+			"warehouse": []interface{}{r.Intn(5), r.Intn(5)},
+		})
+	}
+	listIndex := createIndex(data)
+	bitmapIndex := createIndexWithOptions(data, index.Options{Storage: index.STORAGE_BITMAP})
+	listIndex.Delete(10)
+	bitmapIndex.Delete(10)
+
+	listSearch := search.NewSearch(listIndex)
+	bitmapSearch := search.NewSearch(bitmapIndex)
+
+	filterSets := [][]filter.FilterInterface{
+		{},
+		{&filter.ValueFilter{FieldName: "color", Values: []string{"red", "black"}}},
+		{
+			&filter.ValueIntersectionFilter{FieldName: "warehouse", Values: []string{"1", "2"}},
+			&filter.RangeFilter{FieldName: "size", Values: filter.Range{Min: 2, Max: 6}},
+		},
+		{
+			&filter.ExcludeValueFilter{FieldName: "color", Values: []string{"red"}},
+			&filter.ValueFilter{FieldName: "warehouse", Values: []string{"3"}},
+		},
+	}
+
+	for _, filters := range filterSets {
+		for _, input := range [][]int64{{}, {1, 2, 3, 10, 100, 200, 4000}} {
+			exp, _ := listSearch.Find(filters, input)
+			res, _ := bitmapSearch.Find(filters, input)
+			if !reflect.DeepEqual(exp, res) {
+				t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+			}
+
+			expInfo, _ := listSearch.AggregateFilters(filters, input)
+			info, _ := bitmapSearch.AggregateFilters(filters, input)
+			if !reflect.DeepEqual(expInfo, info) {
+				t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, expInfo)
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package test
+
+import (
+	"github.com/k-samuel/go-faceted-search/pkg/filter"
+	"github.com/k-samuel/go-faceted-search/pkg/search"
+	"github.com/k-samuel/go-faceted-search/pkg/sorter"
+	"sync"
+	"testing"
+)
+
+// run with race detector: go test -race ./test
+func TestConcurrentReadWrite(t *testing.T) {
+	for _, idx := range createStorageIndexes(getIndexTestData()) {
+		facet := search.NewSearch(idx)
+		colors := []string{"black", "white", "yellow"}
+
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := int64(10); i < 200; i++ {
+				idx.Add(i, map[string]interface{}{"color": colors[i%3], "size": i % 10, "group": "D"})
+				idx.Update(i-5, map[string]interface{}{"color": colors[(i+1)%3], "size": i % 5})
+				if i%3 == 0 {
+					idx.Delete(i - 1)
+				}
+				if i%10 == 0 {
+					idx.CommitChanges()
+				}
+			}
+		}()
+
+		for r := 0; r < 4; r++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				srt := sorter.NewIntSorter(idx)
+				for i := 0; i < 50; i++ {
+					filters := []filter.FilterInterface{
+						&filter.ValueFilter{FieldName: "color", Values: []string{"black", "white"}},
+						&filter.RangeFilter{FieldName: "size", Values: filter.Range{Min: 2, Max: 8}},
+					}
+					res, err := facet.Find(filters, []int64{})
+					if err != nil {
+						t.Errorf("find error: %v", err)
+						return
+					}
+					if _, err = facet.AggregateFilters(filters, []int64{}); err != nil {
+						t.Errorf("aggregate error: %v", err)
+						return
+					}
+					if _, err = srt.Sort(res, "size", sorter.SORT_DESC); err != nil {
+						t.Errorf("sort error: %v", err)
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
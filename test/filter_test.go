@@ -0,0 +1,418 @@
+package test
+
+import (
+	"github.com/k-samuel/go-faceted-search/pkg/filter"
+	"github.com/k-samuel/go-faceted-search/pkg/search"
+	"reflect"
+	"testing"
+)
+
+func TestExcludeValueFilter(t *testing.T) {
+	facet := search.NewSearch(createIndex(getIndexTestData()))
+
+	res, _ := facet.Find([]filter.FilterInterface{
+		&filter.ExcludeValueFilter{FieldName: "color", Values: []string{"black", "green"}},
+	}, []int64{})
+	exp := []int64{3, 4}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+	}
+
+	res, _ = facet.Find([]filter.FilterInterface{
+		&filter.ExcludeValueFilter{FieldName: "color", Values: []string{"white"}},
+		&filter.ValueFilter{FieldName: "size", Values: []string{"7"}},
+	}, []int64{})
+	exp = []int64{1, 4, 5}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+	}
+
+	res, _ = facet.Find([]filter.FilterInterface{
+		&filter.ExcludeValueFilter{FieldName: "undefined", Values: []string{"white"}},
+	}, []int64{2, 3})
+	exp = []int64{2, 3}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+	}
+
+	res, _ = facet.Find([]filter.FilterInterface{
+		&filter.ValueFilter{FieldName: "undefined", Values: []string{"white"}},
+		&filter.ValueFilter{FieldName: "size", Values: []string{"7"}},
+	}, []int64{})
+	if len(res) != 0 {
+		t.Errorf("results not match\nGot:\n%v\nExpected:[]", res)
+	}
+}
+
+func TestAggregateExcludeValueFilter(t *testing.T) {
+	facet := search.NewSearch(createIndex(getIndexTestData()))
+
+	info, _ := facet.AggregateFilters([]filter.FilterInterface{
+		&filter.ExcludeValueFilter{FieldName: "color", Values: []string{"yellow"}},
+		&filter.ValueFilter{FieldName: "color", Values: []string{"black", "yellow"}},
+		&filter.ValueFilter{FieldName: "group", Values: []string{"C"}},
+	}, []int64{})
+	exp := map[string]map[string]int{
+		"color": {"black": 1, "yellow": 1},
+		"size":  {"7": 1},
+		"group": {"A": 2, "C": 1},
+	}
+	if !reflect.DeepEqual(exp, info) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, exp)
+	}
+}
+
+func TestValueIntersectionFilter(t *testing.T) {
+	facet := search.NewSearch(createIndex([]map[string]interface{}{
+		{"warehouse": []interface{}{1, 10, 23}, "color": "black"},
+		{"warehouse": []interface{}{1, 23}, "color": "black"},
+		{"warehouse": []interface{}{10}, "color": "white"},
+		{"warehouse": []interface{}{10, 1}, "color": "white"},
+	}))
+
+	res, _ := facet.Find([]filter.FilterInterface{
+		&filter.ValueIntersectionFilter{FieldName: "warehouse", Values: []string{"1", "10"}},
+	}, []int64{})
+	exp := []int64{1, 4}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+	}
+
+	res, _ = facet.Find([]filter.FilterInterface{
+		&filter.ValueFilter{FieldName: "color", Values: []string{"black"}},
+		&filter.ValueIntersectionFilter{FieldName: "warehouse", Values: []string{"1", "10"}},
+	}, []int64{})
+	exp = []int64{1}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+	}
+
+	res, _ = facet.Find([]filter.FilterInterface{
+		&filter.ValueIntersectionFilter{FieldName: "warehouse", Values: []string{"1", "10", "100"}},
+		&filter.ValueFilter{FieldName: "color", Values: []string{"black"}},
+	}, []int64{})
+	if len(res) != 0 {
+		t.Errorf("results not match\nGot:\n%v\nExpected:[]", res)
+	}
+
+	info, _ := facet.AggregateFilters([]filter.FilterInterface{
+		&filter.ValueIntersectionFilter{FieldName: "warehouse", Values: []string{"1", "23"}},
+	}, []int64{})
+	expInfo := map[string]map[string]int{
+		"warehouse": {"1": 3, "10": 3, "23": 2},
+		"color":     {"black": 2},
+	}
+	if !reflect.DeepEqual(expInfo, info) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, expInfo)
+	}
+}
+
+func TestRangeFilter(t *testing.T) {
+	idx := createIndex([]map[string]interface{}{
+		{"viscosity": "15W-40", "price": 100},
+		{"viscosity": "5W-30", "price": 250.5},
+		{"viscosity": 40, "price": 500},
+		{"viscosity": 50, "price": -10},
+		{"viscosity": "NaN", "price": 1000},
+	})
+	facet := search.NewSearch(idx)
+
+	cases := []struct {
+		rng filter.Range
+		exp []int64
+	}{
+		{rng: filter.Range{Min: 100, Max: 500}, exp: []int64{1, 2, 3}},
+		{rng: filter.Range{Min: 100, Type: filter.RANGE_MIN}, exp: []int64{1, 2, 3, 5}},
+		{rng: filter.Range{Max: 250.5, Type: filter.RANGE_MAX}, exp: []int64{1, 2, 4}},
+		{rng: filter.Range{Min: 600, Max: 900}, exp: []int64{}},
+	}
+	for _, c := range cases {
+		res, err := facet.Find([]filter.FilterInterface{&filter.RangeFilter{FieldName: "price", Values: c.rng}}, []int64{})
+		if err != nil {
+			t.Errorf("range filter error: %v", err)
+		}
+		if !reflect.DeepEqual(c.exp, res) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, c.exp)
+		}
+	}
+
+	// non-numeric values are skipped
+	res, err := facet.Find([]filter.FilterInterface{
+		&filter.RangeFilter{FieldName: "viscosity", Values: filter.Range{Min: 0, Max: 45}},
+	}, []int64{})
+	exp := []int64{3}
+	if err != nil || !reflect.DeepEqual(exp, res) {
+		t.Errorf("results not match\nGot:\n%v %v\nExpected:\n%v", res, err, exp)
+	}
+
+	// uncommitted changes
+	idx.Add(6, map[string]interface{}{"price": 300})
+	idx.Delete(3)
+	res, _ = facet.Find([]filter.FilterInterface{
+		&filter.RangeFilter{FieldName: "price", Values: filter.Range{Min: 200, Max: 600}},
+	}, []int64{})
+	exp = []int64{2, 6}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+	}
+}
+
+func TestCompositeFilters(t *testing.T) {
+	type testCase struct {
+		filters []filter.FilterInterface
+		input   []int64
+		exp     []int64
+	}
+	cases := []testCase{
+		{filters: []filter.FilterInterface{
+			&filter.OrFilter{Filters: []filter.FilterInterface{
+				&filter.ValueFilter{FieldName: "group", Values: []string{"B"}},
+				&filter.ValueFilter{FieldName: "size", Values: []string{"8"}},
+			}},
+		}, exp: []int64{2, 3}},
+		{filters: []filter.FilterInterface{
+			&filter.NotFilter{Filter: &filter.ValueFilter{FieldName: "color", Values: []string{"black"}}},
+		}, exp: []int64{3, 4}},
+		{filters: []filter.FilterInterface{
+			&filter.AndFilter{Filters: []filter.FilterInterface{
+				&filter.OrFilter{Filters: []filter.FilterInterface{
+					&filter.ValueFilter{FieldName: "group", Values: []string{"B"}},
+					&filter.ValueFilter{FieldName: "size", Values: []string{"8"}},
+				}},
+				&filter.NotFilter{Filter: &filter.ValueFilter{FieldName: "color", Values: []string{"white"}}},
+			}},
+		}, exp: []int64{2}},
+		{filters: []filter.FilterInterface{
+			&filter.ValueFilter{FieldName: "size", Values: []string{"7"}},
+			&filter.OrFilter{Filters: []filter.FilterInterface{
+				&filter.ValueFilter{FieldName: "group", Values: []string{"A"}},
+				&filter.ValueFilter{FieldName: "color", Values: []string{"yellow"}},
+			}},
+		}, exp: []int64{1, 4}},
+		{filters: []filter.FilterInterface{
+			&filter.NotFilter{Filter: &filter.OrFilter{Filters: []filter.FilterInterface{
+				&filter.ValueFilter{FieldName: "group", Values: []string{"A"}},
+				&filter.ExcludeValueFilter{FieldName: "color", Values: []string{"yellow"}},
+			}}},
+		}, input: []int64{1, 4, 5}, exp: []int64{4}},
+		{filters: []filter.FilterInterface{
+			&filter.NotFilter{Filter: &filter.ValueFilter{FieldName: "undefined", Values: []string{"1"}}},
+		}, exp: []int64{1, 2, 3, 4, 5}},
+		{filters: []filter.FilterInterface{
+			&filter.OrFilter{},
+		}, exp: []int64{}},
+	}
+
+	for _, idx := range createStorageIndexes(getIndexTestData()) {
+		facet := search.NewSearch(idx)
+		for _, c := range cases {
+			res, err := facet.Find(c.filters, c.input)
+			if err != nil || !reflect.DeepEqual(c.exp, res) {
+				t.Errorf("results not match\nGot:\n%v %v\nExpected:\n%v", res, err, c.exp)
+			}
+		}
+
+		info, _ := facet.AggregateFilters([]filter.FilterInterface{
+			&filter.OrFilter{Filters: []filter.FilterInterface{
+				&filter.ValueFilter{FieldName: "group", Values: []string{"B"}},
+				&filter.ValueFilter{FieldName: "size", Values: []string{"8"}},
+			}},
+			&filter.NotFilter{Filter: &filter.ValueFilter{FieldName: "color", Values: []string{"white"}}},
+		}, []int64{})
+		exp := map[string]map[string]int{
+			"color": {"black": 1, "white": 1},
+			"size":  {"7": 3, "8": 1},
+			"group": {"A": 2, "C": 2},
+		}
+		if !reflect.DeepEqual(exp, info) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, exp)
+		}
+
+		info, _ = facet.AggregateFilters([]filter.FilterInterface{
+			&filter.AndFilter{Filters: []filter.FilterInterface{
+				&filter.ValueFilter{FieldName: "size", Values: []string{"7"}},
+				&filter.ValueFilter{FieldName: "color", Values: []string{"black"}},
+			}},
+		}, []int64{})
+		exp = map[string]map[string]int{
+			"color": {"black": 2, "white": 1, "yellow": 1},
+			"size":  {"7": 2, "8": 1},
+			"group": {"A": 1, "C": 1},
+		}
+		if !reflect.DeepEqual(exp, info) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, exp)
+		}
+	}
+}
+
+func TestPrefixWildcardFilters(t *testing.T) {
+	data := []map[string]interface{}{
+		{"sku": "A500", "group": "A"},
+		{"sku": "A5023", "group": "A"},
+		{"sku": "A5-X-23", "group": "B"},
+		{"sku": "A51", "group": "B"},
+		{"sku": "B500", "group": "A"},
+		{"sku": []interface{}{"A50-1", "C23"}, "group": "C"},
+	}
+	cases := []struct {
+		filter filter.FilterInterface
+		exp    []int64
+	}{
+		{&filter.PrefixFilter{FieldName: "sku", Prefixes: []string{"A50"}}, []int64{1, 2, 6}},
+		{&filter.PrefixFilter{FieldName: "sku", Prefixes: []string{"A5-", "B"}}, []int64{3, 5}},
+		{&filter.PrefixFilter{FieldName: "sku", Prefixes: []string{"D"}}, []int64{}},
+		{&filter.WildcardFilter{FieldName: "sku", Patterns: []string{"A5*23"}}, []int64{2, 3}},
+		{&filter.WildcardFilter{FieldName: "sku", Patterns: []string{"?500"}}, []int64{1, 5}},
+		{&filter.WildcardFilter{FieldName: "sku", Patterns: []string{"*23"}}, []int64{2, 3, 6}},
+		{&filter.WildcardFilter{FieldName: "sku", Patterns: []string{"A5?", "A5*"}}, []int64{1, 2, 3, 4, 6}},
+		{&filter.WildcardFilter{FieldName: "sku", Patterns: []string{"A51"}}, []int64{4}},
+	}
+
+	for _, idx := range createStorageIndexes(data) {
+		facet := search.NewSearch(idx)
+
+		for _, c := range cases {
+			res, _ := facet.Find([]filter.FilterInterface{c.filter}, []int64{})
+			if !reflect.DeepEqual(c.exp, res) {
+				t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, c.exp)
+			}
+		}
+
+		res, _ := facet.Find([]filter.FilterInterface{
+			&filter.PrefixFilter{FieldName: "sku", Prefixes: []string{"A5"}},
+			&filter.ValueFilter{FieldName: "group", Values: []string{"B"}},
+		}, []int64{})
+		exp := []int64{3, 4}
+		if !reflect.DeepEqual(exp, res) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+		}
+
+		// values added after commit are found before the next commit
+		idx.Add(7, map[string]interface{}{"sku": "A509"})
+		res, _ = facet.Find([]filter.FilterInterface{
+			&filter.PrefixFilter{FieldName: "sku", Prefixes: []string{"A50"}},
+		}, []int64{})
+		exp = []int64{1, 2, 6, 7}
+		if !reflect.DeepEqual(exp, res) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+		}
+	}
+}
+
+func TestRegexpFilter(t *testing.T) {
+	data := []map[string]interface{}{
+		{"viscosity": "5W-30", "brand": "A"},
+		{"viscosity": "10W-40", "brand": "A"},
+		{"viscosity": "15W-40", "brand": "B"},
+		{"viscosity": "0W-20", "brand": "B"},
+		{"viscosity": "5W-40", "brand": "C"},
+	}
+
+	if _, err := filter.NewRegexpFilter("viscosity", "(5"); err == nil {
+		t.Errorf("expected error for invalid expression")
+	}
+
+	for _, idx := range createStorageIndexes(data) {
+		facet := search.NewSearch(idx)
+
+		re, err := filter.NewRegexpFilter("viscosity", "^(5|10)W-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, _ := facet.Find([]filter.FilterInterface{re}, []int64{})
+		exp := []int64{1, 2, 5}
+		if !reflect.DeepEqual(exp, res) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+		}
+
+		res, _ = facet.Find([]filter.FilterInterface{
+			re,
+			&filter.ValueFilter{FieldName: "brand", Values: []string{"A", "B"}},
+		}, []int64{})
+		exp = []int64{1, 2}
+		if !reflect.DeepEqual(exp, res) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+		}
+
+		info, _ := facet.AggregateFilters([]filter.FilterInterface{re}, []int64{})
+		expInfo := map[string]map[string]int{
+			"viscosity": {"5W-30": 1, "10W-40": 1, "15W-40": 1, "0W-20": 1, "5W-40": 1},
+			"brand":     {"A": 2, "C": 1},
+		}
+		if !reflect.DeepEqual(expInfo, info) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, expInfo)
+		}
+
+		// cached values are updated after field changes, snapshot keeps own values
+		snapshot := search.NewSearch(idx.Snapshot())
+		idx.Add(6, map[string]interface{}{"viscosity": "10W-60", "brand": "C"})
+		idx.Delete(1)
+		idx.CommitChanges()
+		for i := 0; i < 2; i++ {
+			res, _ = facet.Find([]filter.FilterInterface{re}, []int64{})
+			exp = []int64{2, 5, 6}
+			if !reflect.DeepEqual(exp, res) {
+				t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+			}
+			res, _ = snapshot.Find([]filter.FilterInterface{re}, []int64{})
+			exp = []int64{1, 2, 5}
+			if !reflect.DeepEqual(exp, res) {
+				t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+			}
+		}
+	}
+}
+
+func TestExistsMissingFilters(t *testing.T) {
+	data := []map[string]interface{}{
+		{"color": "black", "size": 7},
+		{"color": "black"},
+		{"color": "white", "size": nil},
+		{"color": "white", "size": []interface{}{8, 9}},
+		{"color": "red"},
+	}
+	for _, idx := range createStorageIndexes(data) {
+		facet := search.NewSearch(idx)
+
+		cases := []struct {
+			filters []filter.FilterInterface
+			input   []int64
+			exp     []int64
+		}{
+			{[]filter.FilterInterface{&filter.ExistsFilter{FieldName: "size"}}, []int64{}, []int64{1, 4}},
+			{[]filter.FilterInterface{&filter.MissingFilter{FieldName: "size"}}, []int64{}, []int64{2, 3, 5}},
+			{[]filter.FilterInterface{&filter.MissingFilter{FieldName: "size"}}, []int64{1, 2}, []int64{2}},
+			{[]filter.FilterInterface{
+				&filter.MissingFilter{FieldName: "size"},
+				&filter.ValueFilter{FieldName: "color", Values: []string{"white"}},
+			}, []int64{}, []int64{3}},
+			{[]filter.FilterInterface{&filter.ExistsFilter{FieldName: "undefined"}}, []int64{}, []int64{}},
+			{[]filter.FilterInterface{&filter.MissingFilter{FieldName: "undefined"}}, []int64{}, []int64{1, 2, 3, 4, 5}},
+			{[]filter.FilterInterface{&filter.OrFilter{Filters: []filter.FilterInterface{
+				&filter.MissingFilter{FieldName: "size"},
+				&filter.ValueFilter{FieldName: "size", Values: []string{"7"}},
+			}}}, []int64{}, []int64{1, 2, 3, 5}},
+		}
+		for _, c := range cases {
+			res, _ := facet.Find(c.filters, c.input)
+			if !reflect.DeepEqual(c.exp, res) {
+				t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, c.exp)
+			}
+		}
+
+		// list of field records is updated by index changes
+		idx.Update(4, map[string]interface{}{"color": "white"})
+		idx.Add(6, map[string]interface{}{"size": 10})
+		idx.Delete(1)
+		res, _ := facet.Find([]filter.FilterInterface{&filter.ExistsFilter{FieldName: "size"}}, []int64{})
+		exp := []int64{6}
+		if !reflect.DeepEqual(exp, res) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+		}
+		res, _ = facet.Find([]filter.FilterInterface{&filter.MissingFilter{FieldName: "size"}}, []int64{})
+		exp = []int64{2, 3, 4, 5}
+		if !reflect.DeepEqual(exp, res) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+		}
+	}
+}
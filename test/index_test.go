@@ -0,0 +1,521 @@
+package test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"github.com/k-samuel/go-faceted-search/pkg/filter"
+	"github.com/k-samuel/go-faceted-search/pkg/index"
+	"github.com/k-samuel/go-faceted-search/pkg/search"
+	"hash/crc32"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func createIndex(data []map[string]interface{}) *index.Index {
+	return createIndexWithOptions(data, index.Options{})
+}
+
+func createIndexWithOptions(data []map[string]interface{}, options index.Options) *index.Index {
+	idx := index.NewIndexWithOptions(options)
+	for i, v := range data {
+		idx.Add(int64(i+1), v)
+	}
+	idx.CommitChanges()
+	return idx
+}
+
+// createStorageIndexes - indexes of data with each storage type
+func createStorageIndexes(data []map[string]interface{}) []*index.Index {
+	return []*index.Index{
+		createIndexWithOptions(data, index.Options{Storage: index.STORAGE_LIST}),
+		createIndexWithOptions(data, index.Options{Storage: index.STORAGE_BITMAP}),
+	}
+}
+
+func getIndexTestData() []map[string]interface{} {
+	return []map[string]interface{}{
+		{"color": "black", "size": 7, "group": "A"},
+		{"color": "black", "size": 8, "group": "A"},
+		{"color": "white", "size": 7, "group": "B"},
+		{"color": "yellow", "size": 7, "group": "C"},
+		{"color": "black", "size": 7, "group": "C"},
+	}
+}
+
+func TestIndexDelete(t *testing.T) {
+	idx := createIndex(getIndexTestData())
+	facet := search.NewSearch(idx)
+
+	idx.Delete(3)
+	idx.Delete(100)
+
+	if idx.GetField("color").HasValue("white") {
+		t.Errorf("empty value is not removed")
+	}
+	if idx.HasField("group") && idx.GetField("group").HasValue("B") {
+		t.Errorf("empty value is not removed")
+	}
+
+	res, _ := facet.Find([]filter.FilterInterface{}, []int64{})
+	exp := []int64{1, 2, 4, 5}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+	}
+
+	info, _ := facet.AggregateFilters([]filter.FilterInterface{
+		&filter.ValueFilter{FieldName: "size", Values: []string{"7"}},
+	}, []int64{})
+	expInfo := map[string]map[string]int{
+		"color": {"black": 2, "yellow": 1},
+		"size":  {"7": 3, "8": 1},
+		"group": {"A": 1, "C": 2},
+	}
+	if !reflect.DeepEqual(expInfo, info) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, expInfo)
+	}
+
+	for i := int64(1); i <= 5; i++ {
+		idx.Delete(i)
+	}
+	if len(idx.GetFields()) != 0 {
+		t.Errorf("empty fields are not removed: %v", idx.GetFields())
+	}
+}
+
+func TestIndexDeleteChangedRecords(t *testing.T) {
+	var buf bytes.Buffer
+	createIndex(getIndexTestData()).WriteTo(&buf)
+	// loaded index builds reverse index of record values on the first change
+	idx, err := index.ReadFrom(&buf)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	snapshot := idx.Snapshot()
+
+	idx.Update(1, map[string]interface{}{"color": "red", "size": 10})
+	idx.Add(6, map[string]interface{}{"color": "red", "group": "D"})
+	idx.Delete(6)
+	idx.Update(1, map[string]interface{}{"color": "blue"})
+
+	if idx.GetField("size").HasValue("10") || idx.GetField("group").HasValue("D") || idx.GetField("color").HasValue("red") {
+		t.Errorf("empty value is not removed")
+	}
+	res, _ := search.NewSearch(idx).Find([]filter.FilterInterface{
+		&filter.ValueFilter{FieldName: "color", Values: []string{"blue"}},
+	}, []int64{})
+	exp := []int64{1}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+	}
+
+	// snapshot is not changed
+	res, _ = search.NewSearch(snapshot).Find([]filter.FilterInterface{}, []int64{})
+	exp = []int64{1, 2, 3, 4, 5}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+	}
+	if !snapshot.GetField("color").GetValue("black").IsSorted() || snapshot.GetRecordsCount("color", "black") != 3 {
+		t.Errorf("snapshot value is changed")
+	}
+}
+
+func TestIndexUpdateSortedValues(t *testing.T) {
+	idx := createIndex(getIndexTestData())
+	snapshot := idx.Snapshot()
+
+	idx.Update(2, map[string]interface{}{"color": "blue", "size": 7.5})
+	idx.Update(3, map[string]interface{}{"color": "white", "size": 12})
+	idx.Delete(4)
+
+	numbers := []float64{}
+	for _, v := range idx.GetField("size").GetNumericValues() {
+		numbers = append(numbers, v.Number)
+	}
+	exp := []float64{7, 7.5, 12}
+	if !reflect.DeepEqual(exp, numbers) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", numbers, exp)
+	}
+	names := idx.GetField("color").GetSortedValues()
+	expNames := []string{"black", "blue", "white"}
+	if !reflect.DeepEqual(expNames, names) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", names, expNames)
+	}
+
+	facet := search.NewSearch(idx)
+	res, _ := facet.Find([]filter.FilterInterface{
+		&filter.RangeFilter{FieldName: "size", Values: filter.Range{Min: 7.2, Max: 20}},
+	}, []int64{})
+	expIds := []int64{2, 3}
+	if !reflect.DeepEqual(expIds, res) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, expIds)
+	}
+	res, _ = facet.Find([]filter.FilterInterface{
+		&filter.PrefixFilter{FieldName: "color", Prefixes: []string{"bl"}},
+	}, []int64{})
+	expIds = []int64{1, 2, 5}
+	if !reflect.DeepEqual(expIds, res) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, expIds)
+	}
+
+	// lists of snapshot are not changed
+	names = snapshot.GetField("color").GetSortedValues()
+	expNames = []string{"black", "white", "yellow"}
+	if !reflect.DeepEqual(expNames, names) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", names, expNames)
+	}
+	if len(snapshot.GetField("size").GetNumericValues()) != 2 {
+		t.Errorf("snapshot numeric values are changed")
+	}
+}
+
+func TestIndexAddScalarValues(t *testing.T) {
+	idx := createIndex(getIndexTestData())
+	// reverse index of record values is built by the first Delete
+	idx.Delete(5)
+	idx.Add(6, map[string]interface{}{"color": "red", "size": int64(9), "weight": 1.5, "group": nil})
+	idx.CommitChanges()
+
+	res, _ := search.NewSearch(idx).Find([]filter.FilterInterface{
+		&filter.ValueFilter{FieldName: "weight", Values: []string{"1.5"}},
+		&filter.ValueFilter{FieldName: "size", Values: []string{"9"}},
+	}, []int64{})
+	exp := []int64{6}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+	}
+	if idx.GetRecordsCount("group", "") != 0 || idx.GetField("group").HasValue("") {
+		t.Errorf("nil value is indexed")
+	}
+
+	idx.Delete(6)
+	if idx.HasField("weight") || idx.GetField("color").HasValue("red") || idx.GetField("size").HasValue("9") {
+		t.Errorf("values of deleted record are not removed")
+	}
+}
+
+func TestIndexUpdate(t *testing.T) {
+	idx := createIndex(getIndexTestData())
+	facet := search.NewSearch(idx)
+
+	idx.Update(2, map[string]interface{}{"color": "white", "size": 7, "group": "B"})
+	idx.Update(6, map[string]interface{}{"color": "white", "size": 9})
+
+	for name, field := range idx.GetFields() {
+		for val, value := range field.Values {
+			if !value.IsSorted() {
+				t.Errorf("record list is not sorted %v -> %v: %v", name, val, value.GetIds())
+			}
+			ids := value.GetIds()
+			for i := 1; i < len(ids); i++ {
+				if ids[i-1] >= ids[i] {
+					t.Errorf("record list is not sorted %v -> %v: %v", name, val, ids)
+				}
+			}
+		}
+	}
+
+	res, _ := facet.Find([]filter.FilterInterface{
+		&filter.ValueFilter{FieldName: "color", Values: []string{"white"}},
+	}, []int64{})
+	exp := []int64{2, 3, 6}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+	}
+
+	if idx.GetField("size").HasValue("8") {
+		t.Errorf("empty value is not removed")
+	}
+}
+
+func TestIndexWriteRead(t *testing.T) {
+	idx := createIndex(getIndexTestData())
+	idx.Add(0, map[string]interface{}{"color": "black", "size": []interface{}{7, 9}})
+
+	var buf bytes.Buffer
+	n, err := idx.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("written bytes count not match\nGot:\n%v\nExpected:\n%v", n, buf.Len())
+	}
+	data := buf.Bytes()
+
+	loaded, err := index.ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	idx.CommitChanges()
+	if len(loaded.GetFields()) != len(idx.GetFields()) {
+		t.Fatalf("fields count not match\nGot:\n%v\nExpected:\n%v", len(loaded.GetFields()), len(idx.GetFields()))
+	}
+	for name, field := range idx.GetFields() {
+		for val, value := range field.Values {
+			if !loaded.HasField(name) || !loaded.GetField(name).HasValue(val) {
+				t.Fatalf("loaded index has no value %v -> %v", name, val)
+			}
+			ids := loaded.GetField(name).GetValue(val).GetIds()
+			if !reflect.DeepEqual(value.GetIds(), ids) {
+				t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", ids, value.GetIds())
+			}
+		}
+	}
+
+	corrupted := append([]byte{}, data...)
+	corrupted[len(corrupted)/2] ^= 0xFF
+	if _, err = index.ReadFrom(bytes.NewReader(corrupted)); err == nil {
+		t.Errorf("corrupted data is loaded without error")
+	}
+	if _, err = index.ReadFrom(bytes.NewReader(data[:len(data)-1])); err != index.ErrInvalidFormat {
+		t.Errorf("unexpected error for truncated data: %v", err)
+	}
+	if _, err = index.ReadFrom(bytes.NewReader([]byte("NOT AN INDEX"))); err != index.ErrInvalidFormat {
+		t.Errorf("unexpected error for invalid data: %v", err)
+	}
+	version := append([]byte{}, data...)
+	version[5]++
+	if _, err = index.ReadFrom(bytes.NewReader(version)); err != index.ErrUnsupportedVersion {
+		t.Errorf("unexpected error for unsupported version: %v", err)
+	}
+
+	// version 1 has no display values: field "color", value "black" with record 1
+	v1 := append([]byte("GOFS"), 0, 1, 1, 5, 'c', 'o', 'l', 'o', 'r', 1, 5, 'b', 'l', 'a', 'c', 'k', 1, 2)
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc32.ChecksumIEEE(v1))
+	v1 = append(v1, sum[:]...)
+	loaded, err = index.ReadFrom(bytes.NewReader(v1))
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	if ids := loaded.GetField("color").GetValue("black").GetIds(); !reflect.DeepEqual([]int64{1}, ids) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", ids, []int64{1})
+	}
+}
+
+func TestIndexSnapshot(t *testing.T) {
+	for _, idx := range createStorageIndexes(getIndexTestData()) {
+
+		snapshot := idx.Snapshot()
+		if !snapshot.IsReadOnly() || idx.IsReadOnly() {
+			t.Fatalf("unexpected read-only flags")
+		}
+		facet := search.NewSearch(snapshot)
+		filters := []filter.FilterInterface{&filter.ValueFilter{FieldName: "color", Values: []string{"black"}}}
+		expInfo, _ := facet.AggregateFilters(filters, []int64{})
+
+		idx.Add(6, map[string]interface{}{"color": "black", "size": 9, "group": "D"})
+		idx.Update(1, map[string]interface{}{"color": "white", "size": 7})
+		idx.Delete(2)
+		idx.CommitChanges()
+
+		res, _ := facet.Find(filters, []int64{})
+		exp := []int64{1, 2, 5}
+		if !reflect.DeepEqual(exp, res) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+		}
+		info, _ := facet.AggregateFilters(filters, []int64{})
+		if !reflect.DeepEqual(expInfo, info) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info, expInfo)
+		}
+
+		res, _ = search.NewSearch(idx.Snapshot()).Find(filters, []int64{})
+		exp = []int64{5, 6}
+		if !reflect.DeepEqual(exp, res) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+		}
+	}
+}
+
+func TestIndexBuilder(t *testing.T) {
+	builder := index.NewBuilder(index.NewIndexWithOptions(index.Options{Storage: index.STORAGE_BITMAP}))
+	colors := []string{"black", "white"}
+	filters := []filter.FilterInterface{&filter.ValueFilter{FieldName: "color", Values: []string{"black"}}}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// each batch adds two black records and moves one record to other color
+		for i := int64(0); i < 100; i++ {
+			builder.Add(i*2, map[string]interface{}{"color": "black", "batch": i})
+			builder.Add(i*2+1, map[string]interface{}{"color": "black", "batch": i})
+			if i > 0 {
+				builder.Update(i*2-2, map[string]interface{}{"color": colors[i%2], "batch": i})
+				builder.Update(i*2-1, map[string]interface{}{"color": colors[i%2], "batch": i})
+			}
+			builder.CommitChanges()
+		}
+	}()
+
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				facet := search.NewSearch(builder.Snapshot())
+				res, _ := facet.Find(filters, []int64{})
+				info, _ := facet.AggregateFilters(filters, []int64{})
+				if len(res)%2 != 0 || info["color"]["black"] != len(res) {
+					t.Errorf("partially applied batch is visible: %v %v", res, info)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := builder.Snapshot().Add(1000, map[string]interface{}{"color": "black"}); err != index.ErrReadOnly {
+		t.Errorf("snapshot change is not prohibited")
+	}
+	if err := builder.Snapshot().Delete(1); err != index.ErrReadOnly {
+		t.Errorf("snapshot change is not prohibited")
+	}
+	if err := builder.Delete(1); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestBuilderCommitConcurrentAdd(t *testing.T) {
+	builder := index.NewBuilder(index.NewIndex())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// descending id makes lists unsorted until commit
+		for i := int64(10000); i > 0; i-- {
+			builder.Add(i, map[string]interface{}{"color": "black", "size": i % 10})
+		}
+	}()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		builder.CommitChanges()
+		for name, field := range builder.Snapshot().GetFields() {
+			for val, value := range field.Values {
+				if !value.IsSorted() {
+					t.Fatalf("uncommitted list in snapshot %v -> %v", name, val)
+				}
+			}
+		}
+	}
+}
+
+type testStringer struct{}
+
+func (s testStringer) String() string {
+	return "stringer"
+}
+
+func TestIndexValueTypes(t *testing.T) {
+	idx := index.NewIndexWithOptions(index.Options{Schema: index.Schema{"qty": index.FIELD_INTEGER}})
+	created := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	err := idx.Add(1, map[string]interface{}{
+		"int":     []interface{}{int8(1), int16(2), int32(3), int64(4), uint(5), uint8(6), uint16(7), uint32(8), uint64(9)},
+		"float":   []interface{}{float32(0.1), 0.5},
+		"number":  []interface{}{json.Number("10"), json.Number("1.5")},
+		"created": created,
+		"name":    testStringer{},
+		"missing": nil,
+		"color":   []interface{}{"black", nil},
+		"qty":     json.Number("7"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx.CommitChanges()
+
+	exp := map[string][]string{
+		"int":     {"1", "2", "3", "4", "5", "6", "7", "8", "9"},
+		"float":   {"0.1", "0.5"},
+		"number":  {"1.5", "10"},
+		"created": {"2021-01-02T03:04:05Z"},
+		"name":    {"stringer"},
+		"color":   {"black"},
+		"qty":     {"7"},
+	}
+	res := make(map[string][]string)
+	for name, field := range idx.GetFields() {
+		for value := range field.Values {
+			res[name] = append(res[name], value)
+		}
+		sort.Strings(res[name])
+	}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+	}
+
+	err = idx.Add(2, map[string]interface{}{"color": "white", "size": []interface{}{7, struct{}{}}})
+	var fieldErr *index.FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "size" || !errors.Is(err, index.ErrInvalidValue) {
+		t.Errorf("field error is not reported: %v", err)
+	}
+	if idx.GetField("color").HasValue("white") {
+		t.Errorf("invalid record is added")
+	}
+}
+
+func TestIndexNestedFields(t *testing.T) {
+	record := map[string]interface{}{
+		"brand": "Nike",
+		"features": map[string]interface{}{
+			"color": "black",
+			"size":  []interface{}{7, 8},
+			"sole":  map[string]interface{}{"type": "rubber", "layers": map[string]interface{}{"top": "foam"}},
+		},
+		"offers": []interface{}{
+			map[string]interface{}{"shop": "A", "price": 10},
+			map[string]interface{}{"shop": "B", "price": 12},
+		},
+	}
+
+	cases := []struct {
+		depth int
+		exp   map[string][]string
+	}{
+		{depth: 0, exp: map[string][]string{
+			"brand":    {"Nike"},
+			"features": {"7", "8", "black", "foam", "rubber"},
+			"offers":   {"10", "12", "A", "B"},
+		}},
+		{depth: 2, exp: map[string][]string{
+			"brand":          {"Nike"},
+			"features.color": {"black"},
+			"features.size":  {"7", "8"},
+			"features.sole":  {"foam", "rubber"},
+			"offers.shop":    {"A", "B"},
+			"offers.price":   {"10", "12"},
+		}},
+		{depth: 4, exp: map[string][]string{
+			"brand":                    {"Nike"},
+			"features.color":           {"black"},
+			"features.size":            {"7", "8"},
+			"features.sole.type":       {"rubber"},
+			"features.sole.layers.top": {"foam"},
+			"offers.shop":              {"A", "B"},
+			"offers.price":             {"10", "12"},
+		}},
+	}
+	for _, c := range cases {
+		idx := index.NewIndexWithOptions(index.Options{MaxDepth: c.depth})
+		if err := idx.Add(1, record); err != nil {
+			t.Fatal(err)
+		}
+		res := make(map[string][]string)
+		for name, field := range idx.GetFields() {
+			for value := range field.Values {
+				res[name] = append(res[name], value)
+			}
+			sort.Strings(res[name])
+		}
+		if !reflect.DeepEqual(c.exp, res) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, c.exp)
+		}
+	}
+}
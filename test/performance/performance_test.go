@@ -9,6 +9,7 @@ import (
 	"os"
 	"runtime"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -22,6 +23,8 @@ var testIndex *index.Index
 var datasetFilePrefix = ".test.dataset."
 var results = 1000000
 var datasetFile string
+var testBitmapIndex *index.Index
+var bitmapIndexOnce sync.Once
 
 func init() {
 
@@ -109,6 +112,10 @@ func createFilters() []filter.FilterInterface {
 }
 
 func CreateIndex() *index.Index {
+	return CreateIndexWithOptions(index.Options{})
+}
+
+func CreateIndexWithOptions(options index.Options) *index.Index {
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
 	startM := m.Alloc
@@ -116,7 +123,7 @@ func CreateIndex() *index.Index {
 	var result map[string]interface{}
 
 	var localIndex *index.Index
-	localIndex = index.NewIndex()
+	localIndex = index.NewIndexWithOptions(options)
 
 	file, err := os.Open(datasetFile)
 	check(err)
@@ -127,7 +134,7 @@ func CreateIndex() *index.Index {
 		json.Unmarshal([]byte(scanner.Text()), &result)
 		id := int64(result["id"].(float64))
 		delete(result, "id")
-		localIndex.Add(id, result)
+		check(localIndex.Add(id, result))
 		counter++
 	}
 	localIndex.CommitChanges()
@@ -189,6 +196,44 @@ func BenchmarkAggregateFilters(b *testing.B) {
 	}
 }
 
+func BenchmarkAggregateValues(b *testing.B) {
+	var recordFilter []int64
+	facet := search.NewSearch(testIndex)
+	filters := createFilters()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		facet.AggregateValues(&search.AggregationQuery{Filters: filters, Records: recordFilter})
+	}
+}
+
+// bitmap index is created on demand
+func getBitmapIndex() *index.Index {
+	bitmapIndexOnce.Do(func() {
+		testBitmapIndex = CreateIndexWithOptions(index.Options{Storage: index.STORAGE_BITMAP})
+	})
+	return testBitmapIndex
+}
+
+func BenchmarkFindBitmap(b *testing.B) {
+	var recordFilter []int64
+	facet := search.NewSearch(getBitmapIndex())
+	filters := createFilters()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		facet.Find(filters, recordFilter)
+	}
+}
+
+func BenchmarkAggregateFiltersBitmap(b *testing.B) {
+	var recordFilter []int64
+	facet := search.NewSearch(getBitmapIndex())
+	filters := createFilters()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		facet.AggregateFilters(filters, recordFilter)
+	}
+}
+
 func BenchmarkSort(b *testing.B) {
 	var recordFilter []int64
 	facet := search.NewSearch(testIndex)
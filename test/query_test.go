@@ -0,0 +1,58 @@
+package test
+
+import (
+	"github.com/k-samuel/go-faceted-search/pkg/filter"
+	"github.com/k-samuel/go-faceted-search/pkg/search"
+	"github.com/k-samuel/go-faceted-search/pkg/sorter"
+	"reflect"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	facet := getSearch()
+	facet.GetIndex().Add(7, map[string]interface{}{"vendor": "Samsung", "color": "black"})
+	facet.GetIndex().CommitChanges()
+
+	query := &search.Query{
+		Filters:       []filter.FilterInterface{&filter.ValueFilter{FieldName: "color", Values: []string{"black"}}},
+		SortField:     "price",
+		SortDirection: sorter.SORT_DESC,
+		Sorter:        sorter.NewIntSorter(facet.GetIndex()),
+		Limit:         2,
+	}
+
+	res, err := facet.Query(query)
+	if err != nil {
+		t.Fatalf("query error: %v", err)
+	}
+	exp := &search.QueryResult{Total: 5, Ids: []int64{2, 4}}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+	}
+
+	// records without sort field value are at the end
+	query.Offset = 2
+	query.Limit = 10
+	res, _ = facet.Query(query)
+	exp = &search.QueryResult{Total: 5, Ids: []int64{6, 5, 7}}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+	}
+
+	query.Offset = 10
+	res, _ = facet.Query(query)
+	exp = &search.QueryResult{Total: 5, Ids: []int64{}}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+	}
+
+	res, _ = facet.Query(&search.Query{Records: []int64{5, 1, 3}, SortField: "model"})
+	exp = &search.QueryResult{Total: 3, Ids: []int64{5, 3, 1}}
+	if !reflect.DeepEqual(exp, res) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
+	}
+
+	if _, err = facet.Query(&search.Query{SortField: "undefined"}); err == nil {
+		t.Errorf("sort by undefined field without error")
+	}
+}
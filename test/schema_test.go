@@ -0,0 +1,100 @@
+package test
+
+import (
+	"errors"
+	"github.com/k-samuel/go-faceted-search/pkg/filter"
+	"github.com/k-samuel/go-faceted-search/pkg/index"
+	"github.com/k-samuel/go-faceted-search/pkg/search"
+	"github.com/k-samuel/go-faceted-search/pkg/sorter"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func createSchemaIndex(t *testing.T) *index.Index {
+	idx := index.NewIndexWithOptions(index.Options{Schema: index.Schema{
+		"price":   index.FIELD_FLOAT,
+		"qty":     index.FIELD_INTEGER,
+		"brand":   index.FIELD_KEYWORD,
+		"active":  index.FIELD_BOOL,
+		"created": index.FIELD_DATE,
+	}})
+	data := []map[string]interface{}{
+		{"price": 100, "qty": "7", "brand": "100", "active": true, "created": "2021-01-02"},
+		{"price": "9.5", "qty": 8.0, "brand": "Nike", "active": "false", "created": time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{"price": 10.0, "qty": 7, "brand": "Nike", "active": 1, "created": "2021-01-02T00:00:00Z"},
+	}
+	for i, v := range data {
+		if err := idx.Add(int64(i+1), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	idx.CommitChanges()
+	return idx
+}
+
+func TestIndexSchema(t *testing.T) {
+	idx := createSchemaIndex(t)
+	facet := search.NewSearch(idx)
+
+	type testCase struct {
+		filters []filter.FilterInterface
+		exp     []int64
+	}
+	cases := []testCase{
+		{filters: []filter.FilterInterface{&filter.ValueFilter{FieldName: "qty", Values: []string{"07"}}}, exp: []int64{1, 3}},
+		{filters: []filter.FilterInterface{&filter.ValueFilter{FieldName: "active", Values: []string{"true"}}}, exp: []int64{1, 3}},
+		{filters: []filter.FilterInterface{&filter.ValueFilter{FieldName: "created", Values: []string{"2021-01-02"}}}, exp: []int64{1, 3}},
+		{filters: []filter.FilterInterface{&filter.ValueFilter{FieldName: "price", Values: []string{"100.0"}}}, exp: []int64{1}},
+		// keyword values are not numbers
+		{filters: []filter.FilterInterface{&filter.RangeFilter{FieldName: "brand", Values: filter.Range{Min: 0, Type: filter.RANGE_MIN}}}, exp: []int64{}},
+		{filters: []filter.FilterInterface{&filter.RangeFilter{FieldName: "price", Values: filter.Range{Max: 10, Type: filter.RANGE_MAX}}}, exp: []int64{2, 3}},
+	}
+	for _, c := range cases {
+		res, _ := facet.Find(c.filters, []int64{})
+		if !reflect.DeepEqual(c.exp, res) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, c.exp)
+		}
+	}
+
+	// numeric fields are sorted as numbers
+	page, _ := facet.Query(&search.Query{SortField: "price", SortDirection: sorter.SORT_ASC})
+	exp := []int64{2, 3, 1}
+	if !reflect.DeepEqual(exp, page.Ids) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", page.Ids, exp)
+	}
+
+	info, _ := facet.AggregateSorted(&search.AggregationQuery{Fields: []string{"price"}, Order: search.ORDER_VALUE})
+	expInfo := []search.ValueCount{{Value: "9.5", Count: 1}, {Value: "10", Count: 1}, {Value: "100", Count: 1}}
+	if !reflect.DeepEqual(expInfo, info["price"].Values) {
+		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", info["price"].Values, expInfo)
+	}
+}
+
+func TestIndexSchemaErrors(t *testing.T) {
+	idx := createSchemaIndex(t)
+
+	records := []map[string]interface{}{
+		{"brand": "Puma", "qty": "15W-40"},
+		{"brand": "Puma", "price": "cheap"},
+		{"brand": "Puma", "active": 2},
+		{"brand": "Puma", "created": "yesterday"},
+		{"brand": "Puma", "size": struct{}{}},
+	}
+	for _, record := range records {
+		err := idx.Add(10, record)
+		if !errors.Is(err, index.ErrInvalidValue) {
+			t.Errorf("invalid value is not detected for %v: %v", record, err)
+		}
+	}
+	// record is not partially added
+	if idx.GetField("brand").HasValue("Puma") {
+		t.Errorf("invalid record is added")
+	}
+	if err := idx.Update(1, records[0]); !errors.Is(err, index.ErrInvalidValue) {
+		t.Errorf("invalid value is not detected: %v", err)
+	}
+	if !idx.GetField("brand").HasValue("100") {
+		t.Errorf("record is changed by invalid update")
+	}
+}
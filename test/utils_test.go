@@ -63,3 +63,57 @@ func TestIntersectCountSortedInt(t *testing.T) {
 		t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, exp)
 	}
 }
+
+func TestDiffSortedInt(t *testing.T) {
+	data := []testCaseData{
+		{src: []int64{1, 2}, cmp: []int64{1, 2}, exp: []int64{}},
+		{src: []int64{10, 21, 123, 124}, cmp: []int64{1, 2, 22, 123, 127}, exp: []int64{10, 21, 124}},
+		{src: []int64{1}, cmp: []int64{}, exp: []int64{1}},
+		{src: []int64{1, 7, 8, 9}, cmp: []int64{2, 7}, exp: []int64{1, 8, 9}},
+	}
+	for _, v := range data {
+		res := utils.DiffSortedInt(v.src, v.cmp)
+		if !reflect.DeepEqual(v.exp, res) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, v.exp)
+		}
+	}
+}
+
+func TestHasIntersectSortedInt(t *testing.T) {
+	data := []struct {
+		a   []int64
+		b   []int64
+		exp bool
+	}{
+		{a: []int64{1, 2}, b: []int64{2, 3}, exp: true},
+		{a: []int64{1, 5, 9}, b: []int64{2, 3, 4, 9}, exp: true},
+		{a: []int64{1, 5}, b: []int64{2, 3, 4}, exp: false},
+		{a: []int64{}, b: []int64{1}, exp: false},
+		{a: []int64{3, 70}, b: []int64{1, 2, 4, 5, 6, 7, 8, 9, 10, 11, 20, 30, 40, 50, 60, 69, 70}, exp: true},
+		{a: []int64{1, 2, 4, 5, 6, 7, 8, 9, 10, 11, 20, 30, 40, 50, 60, 69, 70}, b: []int64{3, 12, 71}, exp: false},
+	}
+	for _, v := range data {
+		res := utils.HasIntersectSortedInt(v.a, v.b)
+		if v.exp != res {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, v.exp)
+		}
+	}
+}
+
+func TestUnionSortedInt(t *testing.T) {
+	data := []struct {
+		lists [][]int64
+		exp   []int64
+	}{
+		{lists: [][]int64{}, exp: []int64{}},
+		{lists: [][]int64{{1, 3}}, exp: []int64{1, 3}},
+		{lists: [][]int64{{1, 3, 5}, {2, 3, 6}}, exp: []int64{1, 2, 3, 5, 6}},
+		{lists: [][]int64{{5}, {}, {1, 5, 9}, {2, 9}, {0, 10}}, exp: []int64{0, 1, 2, 5, 9, 10}},
+	}
+	for _, v := range data {
+		res := utils.UnionSortedInt(v.lists)
+		if !reflect.DeepEqual(v.exp, res) {
+			t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", res, v.exp)
+		}
+	}
+}